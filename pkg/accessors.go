@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+var errorType = reflect.TypeFor[error]()
+
+// fieldAccessors holds the methods used to read and write an unexported field, see WithAccessors.
+type fieldAccessors struct {
+	getter reflect.Value
+	setter reflect.Value
+}
+
+// getterName returns the name of the method used to read the field, the capitalized field name by default.
+func getterName(field reflect.StructField, tag FieldTag) string {
+	if tag.Opts.Getter != "" {
+		return tag.Opts.Getter
+	}
+	runes := []rune(field.Name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// setterName returns the name of the method used to write the field, the capitalized field name prefixed with "Set"
+// by default.
+func setterName(field reflect.StructField, tag FieldTag) string {
+	if tag.Opts.Setter != "" {
+		return tag.Opts.Setter
+	}
+	return "Set" + getterName(field, FieldTag{})
+}
+
+// resolveAccessors looks up the getter and setter methods of the field in the struct value, using a pointer to the
+// struct when addressable so pointer receivers are found as well.
+// The getter must take no arguments and return the field type, while the setter must take the field type and
+// return nothing or an error.
+func resolveAccessors(structValue reflect.Value, field reflect.StructField, tag FieldTag) (*fieldAccessors, error) {
+	receiver := structValue
+	if structValue.CanAddr() {
+		receiver = structValue.Addr()
+	}
+
+	getter := receiver.MethodByName(getterName(field, tag))
+	setter := receiver.MethodByName(setterName(field, tag))
+	invalid := []string{}
+	if !getter.IsValid() || !isGetterOf(getter.Type(), field.Type) {
+		invalid = append(invalid, getterName(field, tag))
+	}
+	if !setter.IsValid() || !isSetterOf(setter.Type(), field.Type) {
+		invalid = append(invalid, setterName(field, tag))
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("%s: %s", ERROR_INVALID_ACCESSOR, strings.Join(invalid, ", "))
+	}
+	return &fieldAccessors{getter: getter, setter: setter}, nil
+}
+
+func isGetterOf(method reflect.Type, t reflect.Type) bool {
+	return method.NumIn() == 0 && method.NumOut() == 1 && method.Out(0) == t
+}
+
+func isSetterOf(method reflect.Type, t reflect.Type) bool {
+	returnsNothing := method.NumOut() == 0
+	returnsError := method.NumOut() == 1 && method.Out(0) == errorType
+	return method.NumIn() == 1 && method.In(0) == t && (returnsNothing || returnsError)
+}
+
+// get returns an addressable copy of the value returned by the getter.
+func (a *fieldAccessors) get() reflect.Value {
+	result := a.getter.Call(nil)[0]
+	value := reflect.New(result.Type()).Elem()
+	value.Set(result)
+	return value
+}
+
+// set calls the setter with the provided value, returning the setter error if any.
+func (a *fieldAccessors) set(value reflect.Value) error {
+	result := a.setter.Call([]reflect.Value{value})
+	if len(result) == 0 || result[0].IsNil() {
+		return nil
+	}
+	err, _ := result[0].Interface().(error)
+	return err
+}
@@ -0,0 +1,251 @@
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BatchError holds the errors of the elements that failed to convert in a batch conversion, see UnmarshalSlice.
+type BatchError struct {
+	// Errors maps the index of every failed element, or its key when converting maps, to its error
+	Errors map[any]error
+}
+
+func (e *BatchError) Error() string {
+	keys := make([]any, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, compareKeys)
+
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = fmt.Sprintf("[%v] %s", key, e.Errors[key])
+	}
+	return fmt.Sprintf("%s: %s", ERROR_BATCH_FAILED, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed elements, so they can be inspected with errors.Is and errors.As.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// compareKeys orders the element keys, indexes by their value and any other key by its string representation.
+func compareKeys(a, b any) int {
+	idxA, okA := a.(int)
+	idxB, okB := b.(int)
+	if okA && okB {
+		return cmp.Compare(idxA, idxB)
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// batch holds the elements of a batch conversion, see runBatch.
+type batch struct {
+	size    int
+	srcType reflect.Type
+	dstType reflect.Type
+	// element returns the source and destination of the element at the index, not ok when the source is nil
+	element func(idx int) (src any, dst any, ok bool)
+	// key returns the index or key reported in the errors of the element at the index
+	key func(idx int) any
+	// store sets the element at the index into the destination, when it can't be loaded in place
+	store func(idx int)
+}
+
+// sliceBatch returns the batch converting every element of the source slice or array into the element at the same
+// index of the destination slice, which is replaced by a new slice of the source length.
+func sliceBatch(src interface{}, dst interface{}) (*batch, error) {
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Kind() != reflect.Slice && srcValue.Kind() != reflect.Array {
+		return nil, errors.New(ERROR_INVALID_SLICE_BATCH)
+	}
+	if assertNonNilPointer(dst) != nil || reflect.ValueOf(dst).Elem().Kind() != reflect.Slice {
+		return nil, errors.New(ERROR_INVALID_SLICE_BATCH)
+	}
+
+	list := reflect.ValueOf(dst).Elem()
+	list.Set(reflect.MakeSlice(list.Type(), srcValue.Len(), srcValue.Len()))
+	return &batch{
+		size:    srcValue.Len(),
+		srcType: srcValue.Type().Elem(),
+		dstType: list.Type().Elem(),
+		element: func(idx int) (any, any, bool) {
+			return elementSource(srcValue.Index(idx), list.Index(idx))
+		},
+		key: func(idx int) any { return idx },
+	}, nil
+}
+
+// mapBatch returns the batch converting every value of the source map into the value at the same key of the
+// destination map, which is created when nil.
+func mapBatch(src interface{}, dst interface{}) (*batch, error) {
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Kind() != reflect.Map || assertNonNilPointer(dst) != nil {
+		return nil, errors.New(ERROR_INVALID_MAP_BATCH)
+	}
+	dstMap := reflect.ValueOf(dst).Elem()
+	if dstMap.Kind() != reflect.Map || !srcValue.Type().Key().AssignableTo(dstMap.Type().Key()) {
+		return nil, errors.New(ERROR_INVALID_MAP_BATCH)
+	}
+
+	if dstMap.IsNil() {
+		dstMap.Set(reflect.MakeMapWithSize(dstMap.Type(), srcValue.Len()))
+	}
+	keys := srcValue.MapKeys()
+	values := make([]reflect.Value, len(keys))
+	for i := range values {
+		values[i] = reflect.New(dstMap.Type().Elem()).Elem()
+	}
+	return &batch{
+		size:    len(keys),
+		srcType: srcValue.Type().Elem(),
+		dstType: dstMap.Type().Elem(),
+		element: func(idx int) (any, any, bool) {
+			return elementSource(srcValue.MapIndex(keys[idx]), values[idx])
+		},
+		key:   func(idx int) any { return keys[idx].Interface() },
+		store: func(idx int) { dstMap.SetMapIndex(keys[idx], values[idx]) },
+	}, nil
+}
+
+// elementSource returns the source element, and a pointer to the destination element, allocating it when it's a
+// pointer. Nil sources are not converted, leaving the destination element empty.
+func elementSource(src reflect.Value, dst reflect.Value) (any, any, bool) {
+	if isNilValue(src) {
+		return nil, nil, false
+	}
+	if dst.Kind() == reflect.Ptr {
+		dst.Set(reflect.New(dst.Type().Elem()))
+		return src.Interface(), dst.Interface(), true
+	}
+	return src.Interface(), dst.Addr().Interface(), true
+}
+
+// runBatch converts every element of the batch with convert, spreading them over the workers set with WithWorkers.
+// Every element is converted even when others fail, and the failed ones are reported in a BatchError.
+// It errors with the context error when the context is done before converting every element.
+func runBatch(b *batch, opts *options, convert func(src any, dst any, opts *options) error) error {
+	errs := make([]error, b.size)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range max(opts.workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				if src, dst, ok := b.element(idx); ok {
+					errs[idx] = convert(src, dst, opts.element())
+				}
+			}
+		}()
+	}
+	sendIndexes(opts.ctx, indexes, b.size)
+	wg.Wait()
+	if err := opts.ctx.Err(); err != nil {
+		return err
+	}
+
+	failed := map[any]error{}
+	for idx, err := range errs {
+		if b.store != nil {
+			b.store(idx)
+		}
+		if err != nil {
+			failed[b.key(idx)] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchError{Errors: failed}
+	}
+	return nil
+}
+
+// sendIndexes sends the indexes of the batch elements to the workers, until every index is sent or the context is
+// done, and closes the channel.
+func sendIndexes(ctx context.Context, indexes chan<- int, size int) {
+	defer close(indexes)
+	for idx := range size {
+		select {
+		case indexes <- idx:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// decodeWith loads the destination with the values of the source using the given options, see Unmarshal.
+func decodeWith(src any, dst any, opts *options) error {
+	decoder := &StructDecoder{}
+	if err := decoder.init(src, dst, opts); err != nil {
+		return err
+	}
+	return decoder.Run()
+}
+
+// encodeWith loads the destination with the values of the source using the given options, see Marshal.
+func encodeWith(src any, dst any, opts *options) error {
+	encoder := &StructEncoder{}
+	if err := encoder.init(src, dst, opts); err != nil {
+		return err
+	}
+	return encoder.Run()
+}
+
+// UnmarshalSlice loads every element of the destination slice with the values of the element of the source slice or
+// array at the same index, see Unmarshal. The destination is replaced by a new slice of the source length, and nil
+// source elements are left empty.
+// The options, and the fields of every struct type, are resolved once for the whole batch and reused for every
+// element, and the elements can be converted concurrently with the WithWorkers option. Every element is converted
+// even when others fail, the failed ones are reported in a BatchError.
+func UnmarshalSlice(src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalSlice(src, dst, opts...)
+}
+
+// MarshalSlice loads every element of the destination slice with the values of the element of the source slice or
+// array at the same index, see Marshal and UnmarshalSlice.
+func MarshalSlice(src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.MarshalSlice(src, dst, opts...)
+}
+
+// UnmarshalMapValues loads the destination map with every value of the source map, converted at the same key, see
+// Unmarshal and UnmarshalSlice. The destination is created when nil, and the failed elements are reported by key.
+func UnmarshalMapValues(src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalMapValues(src, dst, opts...)
+}
+
+// MarshalMapValues loads the destination map with every value of the source map, converted at the same key, see
+// Marshal and UnmarshalMapValues.
+func MarshalMapValues(src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.MarshalMapValues(src, dst, opts...)
+}
+
+// UnmarshalSliceContext works like UnmarshalSlice, but stops the conversion as soon as the context is done.
+func UnmarshalSliceContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalSliceContext(ctx, src, dst, opts...)
+}
+
+// MarshalSliceContext works like MarshalSlice, but stops the conversion as soon as the context is done.
+func MarshalSliceContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.MarshalSliceContext(ctx, src, dst, opts...)
+}
+
+// UnmarshalMapValuesContext works like UnmarshalMapValues, but stops the conversion as soon as the context is done.
+func UnmarshalMapValuesContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalMapValuesContext(ctx, src, dst, opts...)
+}
+
+// MarshalMapValuesContext works like MarshalMapValues, but stops the conversion as soon as the context is done.
+func MarshalMapValuesContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.MarshalMapValuesContext(ctx, src, dst, opts...)
+}
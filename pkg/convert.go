@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"reflect"
+)
+
+// Convert returns a new value of type D loaded with the values of the source. D is loaded with Unmarshal when it
+// declares mapped fields, otherwise it's loaded with Marshal using the field paths of the source, eg
+// Convert[*Workload](deployment) or Convert[appsv1.Deployment](workload).
+// D can be a pointer type, in which case a new value is allocated. The provided options are applied to the whole
+// conversion.
+func Convert[D any](src any, opts ...Option) (D, error) {
+	o := newOptions(opts...)
+	convert := encodeWith
+	if t := derefType(reflect.TypeFor[D]()); t.Kind() == reflect.Struct && declaresMappedFields(t, o.tagKeys) {
+		convert = decodeWith
+	}
+	return newLoaded[D](func(dst any) error {
+		return convert(src, dst, o)
+	})
+}
+
+// TypedMapper converts between the S structs declaring the field paths and D values, see NewMapper.
+// It is safe for concurrent use.
+type TypedMapper[S any, D any] struct {
+	opts *options
+}
+
+// NewMapper returns a TypedMapper converting between S, the struct declaring the field paths, and D, applying the
+// provided options to every conversion, eg NewMapper[Workload, appsv1.Deployment](). The options, and the fields of
+// S matched against D in both directions, are resolved once and reused by every conversion. The fields of the
+// structs nested in S are resolved on first use and reused as well.
+func NewMapper[S any, D any](opts ...Option) *TypedMapper[S, D] {
+	m := &TypedMapper[S, D]{opts: newOptions(opts...)}
+	m.resolve()
+	return m
+}
+
+// resolve resolves the fields of S against the type targets of D when marshalling and unmarshalling.
+// Fields failing to resolve are not cached, so the conversions resolve them again and report the error.
+func (m *TypedMapper[S, D]) resolve() {
+	structType := derefType(reflect.TypeFor[S]())
+	if structType.Kind() != reflect.Struct {
+		return
+	}
+	src, _ := newLoaded[D](func(any) error { return nil })
+	targets := []typeTarget{targetOf(reflect.New(reflect.TypeFor[D]()).Interface(), m.opts), sourceTarget(src, m.opts)}
+	for _, target := range targets {
+		_, _ = m.opts.plans.structPlan(structType, target, m.opts)
+	}
+}
+
+// To returns a new D loaded with the values of the source, see Marshal.
+func (m *TypedMapper[S, D]) To(src S) (D, error) {
+	return newLoaded[D](func(dst any) error {
+		return encodeWith(src, dst, m.opts.element())
+	})
+}
+
+// From returns a new S loaded with the values of the source, see Unmarshal.
+func (m *TypedMapper[S, D]) From(src D) (S, error) {
+	return newLoaded[S](func(dst any) error {
+		return decodeWith(src, dst, m.opts.element())
+	})
+}
+
+// newLoaded returns a new value of type T loaded by load, which is given a pointer to the value, or the value itself
+// when T is a pointer type, allocated beforehand. It returns the zero value when load errors.
+func newLoaded[T any](load func(dst any) error) (T, error) {
+	var value T
+	dst := any(&value)
+	if v := reflect.ValueOf(&value).Elem(); v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		dst = value
+	}
+	if err := load(dst); err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
@@ -1,18 +1,67 @@
 package pkg
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"reflect"
+	"strings"
 )
 
-func toMap(from interface{}, into map[string]interface{}) (err error) {
-	bytes, err := json.Marshal(from)
+// document is a source already decoded into a map[string]interface{}, which is used as is.
+type document map[string]interface{}
+
+// toMap loads the into map with the values of the from source, see rawDocument.
+// Sources declaring xml documents are read through encoding/xml when the WithXML option is set, see isXMLType, in
+// which case it reports the source was read as a xml document.
+func toMap(from interface{}, into map[string]interface{}, opts *options) (isXML bool, err error) {
+	if opts.xml && isXMLType(reflect.TypeOf(from)) {
+		data, err := xml.Marshal(from)
+		if err != nil {
+			return false, err
+		}
+		if from, err = decodeXMLDocument(bytes.NewReader(data)); err != nil {
+			return false, err
+		}
+		isXML = true
+	}
+	if doc, ok := from.(document); ok {
+		maps.Copy(into, doc)
+		return isXML, nil
+	}
+	bytes, err := rawDocument(from)
 	if err != nil {
-		return err
+		return isXML, err
 	}
-	err = json.Unmarshal(bytes, &into)
-	return err
+	return isXML, json.Unmarshal(bytes, &into)
+}
+
+// rawDocument returns the json document of the source. Sources holding raw json documents, []byte, json.RawMessage
+// or io.Reader, are returned as read, and any other source, like structs or map[string]any, is marshalled to json.
+func rawDocument(from interface{}) ([]byte, error) {
+	switch src := from.(type) {
+	case json.RawMessage:
+		return src, nil
+	case []byte:
+		return src, nil
+	case io.Reader:
+		return io.ReadAll(src)
+	}
+	return json.Marshal(from)
+}
+
+// isRawSource reports whether the source is a raw document, like a json document, a map[string]any or a decoded
+// yaml or xml document, which has no type to match against the `types<>` tag option, see rawDocument.
+func isRawSource(src interface{}) bool {
+	switch src.(type) {
+	case json.RawMessage, []byte, io.Reader, document:
+		return true
+	}
+	return src != nil && reflect.TypeOf(src).Kind() == reflect.Map
 }
 
 func assertNonNilPointer(check interface{}) (err error) {
@@ -29,130 +78,292 @@ func assertNonNilPointer(check interface{}) (err error) {
 type StructDecoder struct {
 	src          interface{}
 	dst          interface{}
-	typeRestrain string
+	typeRestrain typeTarget
+	opts         *options
 }
 
 // Init initializes the StructBuilder with the provided source and destination interfaces.
 // It first checks that the dst interface is a non-nil pointer, and returns an error if it is not.
 // It then sets the src, dst, and typeRestrain fields of the StructBuilder.
-// The typeRestrain field is set to the type name of the src interface.
+// The typeRestrain field is set to the type of the src interface, unless a type name is set with AsType.
+// The provided options are applied to the whole conversion.
 // This function returns an error if the dst interface is not a non-nil pointer.
-func (sb *StructDecoder) Init(src interface{}, dst interface{}) (err error) {
-	if err = assertNonNilPointer(dst); err != nil {
+func (sb *StructDecoder) Init(src interface{}, dst interface{}, opts ...Option) (err error) {
+	return sb.init(src, dst, newOptions(opts...))
+}
+
+func (sb *StructDecoder) init(src interface{}, dst interface{}, opts *options) error {
+	if err := assertNonNilPointer(dst); err != nil {
 		return errors.New("dst must be a non-nil pointer")
 	}
 
 	sb.src = src
 	sb.dst = dst
-	sb.typeRestrain = getTypeName(sb.src)
+	sb.opts = opts
+	sb.typeRestrain = sourceTarget(src, opts)
+	return nil
+}
 
-	return err
+// sourceTarget returns the type target of the source when unmarshalling, see targetOf.
+func sourceTarget(src interface{}, opts *options) typeTarget {
+	if isRawSource(src) {
+		// raw sources are only matched by the type name set with AsType
+		return targetOf(nil, opts)
+	}
+	return targetOf(src, opts)
 }
 
-// Run generates a map[string]interface{} representation of the dst struct, using the values from the src interface{}.
-// It first converts the src interface{} to a map[string]interface{} using the toMap function.
-// It then recursively generates the map[string]interface{} representation of the dst struct by calling the generate
-// function.
-// Finally, it marshals the generated map[string]interface{} to JSON and unmarshals it into the dst pointer.
+// Run loads the dst struct with the values from the src interface{}.
+// It first converts the src interface{} to a map[string]interface{} using the toMap function, so src can be a
+// struct, a map[string]any, or a raw json document.
+// It then recursively sets every field of the dst struct by calling the generate function.
 // The function returns an error if any errors occur during the generation process.
 func (sb StructDecoder) Run() (err error) {
 	input := map[string]interface{}{}
-	if err := toMap(sb.src, input); err != nil {
+	isXML, err := toMap(sb.src, input, sb.opts)
+	if err != nil {
 		return err
 	}
-
-	reflectedDst := reflect.ValueOf(sb.dst)
-	if reflectedDst.Kind() == reflect.Ptr {
-		reflectedDst = reflectedDst.Elem()
+	if isXML {
+		// read the source as a xml document in this conversion only, the options may be shared with others
+		opts := *sb.opts
+		opts.xmlDocument = true
+		sb.opts = &opts
 	}
-
-	out := map[string]interface{}{}
-	if err = sb.generate(input, sb.typeRestrain, reflectedDst, out); err != nil {
-		return err
+	if sb.opts.documentTag != nil {
+		input, _ = sb.opts.documentTag.renameKeys(input, reflect.TypeOf(sb.src), true, nil, nil).(map[string]interface{})
 	}
 
-	if outEnc, err := json.Marshal(out); err != nil {
-		return err
-	} else {
-		return json.Unmarshal(outEnc, sb.dst)
+	_, err = sb.generate(input, reflect.ValueOf(sb.dst))
+	return err
+}
+
+// generate recursively sets the fields of the dst struct, using the values from the src map[string]interface{}, see
+// generateFields. Once every field is set, the AfterUnmarshal hook of the dst struct is called.
+// It reports whether any of the dst fields paths is present in the src map.
+func (sb StructDecoder) generate(src map[string]interface{}, dst reflect.Value, parents ...string) (bool, error) {
+	for dst.Kind() == reflect.Ptr {
+		dst = dst.Elem()
 	}
+	found, err := sb.generateFields(src, dst, parents...)
+	if err != nil {
+		return found, err
+	}
+	return found, afterUnmarshal(sb.opts.ctx, dst)
 }
 
-// generate recursively generates a map[string]interface{} representation of the dst struct, using the values from the
-// src map[string]interface{}.
-// It iterates through each field in the dst struct, and for each field:
-// - If the field is a struct, it recursively calls generate() to generate a map[string]interface{} for that struct.
-// - If the field is a slice of structs, it calls generateSlice() to generate a slice of map[string]interface{} for that
-// slice.
-// - Otherwise, it gets the value for that field from the src map and adds it to the into map.
-// The function returns an error if any errors occur during the generation process.
-func (sb StructDecoder) generate(
+// generateFields iterates through each field in the dst struct, and for each field:
+// - If the field is an interface registered as union, it calls generateUnion() to set its concrete value.
+// - If the field is a struct, it calls generateStruct() to set the fields of that struct.
+// - If the field is a slice, array or map holding structs, it calls generateElements() to set every element.
+// - Otherwise, it gets the value for that field from the src map and sets it into the field.
+// It reports whether any of the fields paths is present in the src map.
+// The function returns an error if any errors occur during the generation process, if a field path is not found
+// in the src map when using strict mode, or if the conversion context is done.
+func (sb StructDecoder) generateFields(
 	src map[string]interface{},
-	typeRestrain string,
 	dst reflect.Value,
-	into map[string]interface{},
 	parents ...string,
-) error {
-	if dst.Kind() == reflect.Ptr {
-		dst = dst.Elem()
+) (bool, error) {
+	if err := sb.opts.ctx.Err(); err != nil {
+		return false, err
 	}
 
-	for i := range dst.NumField() {
-		field := &Field{}
-		if err := field.Init(i, dst, sb.typeRestrain); err != nil {
-			return err
+	fields, err := structFields(dst, sb.typeRestrain, parents, sb.opts, true)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	for _, field := range fields {
+		if !field.Value.CanSet() {
+			continue
 		}
 
-		if field.Skip {
+		if field.IsUnion() {
+			nested, err := sb.generateUnion(src, field.Value, field.GetPathAsParent())
+			if err == nil && !nested && sb.opts.strict {
+				path := strings.Join(field.Path, sb.opts.syntax.PathSeparator)
+				err = fmt.Errorf("%s: %s", ERROR_PATH_NOT_FOUND, path)
+			}
+			if err == nil {
+				err = field.Store()
+			}
+			if err != nil {
+				return found, err
+			}
+			if nested {
+				field.Allocate()
+			}
+			found = found || nested
 			continue
 		}
-		field.ChRoot(parents)
 
-		var value any
 		if field.IsStruct() {
-			val := map[string]interface{}{}
-			if err := sb.generate(src, typeRestrain, field.Value, val, field.GetPathAsParent()...); err != nil {
-				return err
-			} else {
-				value = val
+			nested, err := sb.generateStruct(src, field)
+			if err == nil {
+				err = field.Store()
 			}
-		} else {
-			value = field.GetValueFromMap(src)
+			if nested {
+				field.Allocate()
+			}
+			if err != nil {
+				return found, err
+			}
+			found = found || nested
+			continue
+		}
+
+		value := field.GetValueFromMap(src)
+		if value == nil && sb.opts.strict {
+			path := strings.Join(field.Path, sb.opts.syntax.PathSeparator)
+			return found, fmt.Errorf("%s: %s", ERROR_PATH_NOT_FOUND, path)
 		}
 		if value == nil {
 			continue
 		}
+		found = true
+		field.Allocate()
+
+		if field.IsStructContainer() {
+			err = sb.generateElements(src, value, field.Value, field.Path)
+		} else {
+			err = field.SetValue(value)
+		}
+		if err == nil {
+			err = field.Store()
+		}
+		if err != nil {
+			return found, err
+		}
+	}
+
+	return found, nil
+}
+
+// generateStruct sets the fields of a nested struct field, which can be a pointer, or a chain of pointers like **T,
+// to the struct. Nil pointers are only allocated when any of the nested fields paths is present in the src map, so
+// absent subtrees are left nil.
+// It reports whether any of the nested fields paths is present in the src map.
+func (sb StructDecoder) generateStruct(src map[string]interface{}, field *Field) (bool, error) {
+	target := field.Value
+	for target.Kind() == reflect.Ptr && !target.IsNil() {
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Ptr {
+		return sb.generate(src, target, field.GetPathAsParent()...)
+	}
+
+	// load the struct into a new pointer chain, only set into the field when any path is found
+	chain := reflect.New(target.Type()).Elem()
+	value := chain
+	for value.Kind() == reflect.Ptr {
+		value.Set(reflect.New(value.Type().Elem()))
+		value = value.Elem()
+	}
+	found, err := sb.generateFields(src, value, field.GetPathAsParent()...)
+	if err != nil || !found {
+		return found, err
+	}
+	target.Set(chain)
+	return found, afterUnmarshal(sb.opts.ctx, value)
+}
 
-		if field.IsStructSlice() {
-			val := []any{}
-			if err := sb.generateSlice(value.([]interface{}), field, typeRestrain, &val); err != nil {
+// generateUnion sets the interface target with a new value of the union variant found at path in the src map,
+// setting its fields by calling generate() rooted at the variant path. The target is left untouched when no variant
+// is found.
+// It reports whether a variant was found.
+func (sb StructDecoder) generateUnion(src map[string]interface{}, target reflect.Value, path []string) (bool, error) {
+	union, _ := sb.opts.types.union(target.Type())
+	name, err := union.selectVariant(src, path, sb.opts)
+	if err != nil || name == "" {
+		return false, err
+	}
+
+	variant := union.variants[name]
+	value := reflect.New(derefType(variant))
+	if _, err := sb.generate(src, value, union.rootOf(name, path, sb.opts)...); err != nil {
+		return true, err
+	}
+	if variant.Kind() != reflect.Ptr {
+		value = value.Elem()
+	}
+	target.Set(value)
+	return true, nil
+}
+
+// generateElements sets the target with the raw value read from the src map, recursing through any combination of
+// slices, arrays, maps and pointers until reaching the structs held by them, which are set by calling generate(), or
+// the interfaces registered as union, which are set by calling generateUnion().
+// Every struct is rooted at its element path, built from the container path by appending the element index for
+// slices and arrays (eg "list[0]" or "list[0][1]") and the element key for maps (eg "volumes.data"), so the
+// element fields can still reach the whole src.
+// Values not matching the target shape are set through decodeValue.
+func (sb StructDecoder) generateElements(
+	src map[string]interface{},
+	value any,
+	target reflect.Value,
+	path []string,
+) error {
+	list, isList := asList(value, target.Type(), sb.opts)
+	data, isMap := value.(map[string]interface{})
+
+	switch kind := target.Kind(); {
+	case value == nil:
+		return nil
+	case kind == reflect.Ptr:
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return sb.generateElements(src, value, target.Elem(), path)
+	case kind == reflect.Slice && isList:
+		slice := reflect.MakeSlice(target.Type(), len(list), len(list))
+		for i := range list {
+			if err := sb.generateElements(src, list[i], slice.Index(i), elementPath(path, i)); err != nil {
 				return err
-			} else {
-				value = val
 			}
 		}
-		into[field.stfield.Name] = value
+		target.Set(slice)
+	case kind == reflect.Array && isList:
+		for i := range min(len(list), target.Len()) {
+			if err := sb.generateElements(src, list[i], target.Index(i), elementPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case kind == reflect.Map && isMap:
+		return sb.generateMap(src, data, target, path)
+	case kind == reflect.Interface && containsStructs(target.Type(), sb.opts):
+		_, err := sb.generateUnion(src, target, path)
+		return err
+	case kind == reflect.Struct && !isPlainValue(target.Type(), sb.opts):
+		_, err := sb.generate(src, target, path...)
+		return err
+	default:
+		return decodeValue(target, value, sb.opts)
 	}
-
 	return nil
 }
 
-// generateSlice recursively generates a slice of map[string]interface{} representations of the elements in the value
-// slice, using the dst struct type specified by the field parameter.
-// It iterates through each element in the value slice, and for each element:
-// - It creates a new map[string]interface{} to hold the representation of the element.
-// - It calls the generate() function to recursively generate the map[string]interface{} representation of the element,
-// using the element's map[string]interface{} value and the dst struct type.
-// - It appends the generated map[string]interface{} to the out slice.
-// The function returns an error if any errors occur during the generation process.
-func (sb StructDecoder) generateSlice(value []interface{}, field *Field, typeRestrain string, out *[]any) error {
-	dstType := field.Value.Type().Elem()
-	for i := range value {
-		val := map[string]interface{}{}
-		if err := sb.generate(value[i].(map[string]interface{}), typeRestrain, reflect.New(dstType).Elem(), val); err != nil {
+// generateMap sets the target map with a new value for every key in the value map, see generateElements.
+func (sb StructDecoder) generateMap(
+	src map[string]interface{},
+	value map[string]interface{},
+	target reflect.Value,
+	path []string,
+) error {
+	mapType := target.Type()
+	result := reflect.MakeMapWithSize(mapType, len(value))
+	for key, raw := range value {
+		mapKey, err := decodeMapKey(mapType.Key(), key)
+		if err != nil {
+			return err
+		}
+		elem := reflect.New(mapType.Elem()).Elem()
+		if err := sb.generateElements(src, raw, elem, keyPath(path, key)); err != nil {
 			return err
 		}
-		*out = append(*out, val)
+		result.SetMapIndex(mapKey, elem)
 	}
+	target.Set(result)
 	return nil
 }
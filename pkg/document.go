@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"reflect"
+	"strings"
+)
+
+// TagNamer extracts the document key of a field from the value of its tag, returning an empty string when the tag
+// doesn't set a name, in which case the go field name is used. Returning SKIP_FIELD excludes the field.
+type TagNamer func(tag string) string
+
+// TagName is the TagNamer of tags starting with the field name followed by comma separated options, like the json,
+// yaml, bson or xml tags, eg `yaml:"name,omitempty"`.
+func TagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// ProtobufJSONName is the TagNamer of protobuf tags, using the json name of the field, eg
+// `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3"`, or its proto name when no json name is set.
+func ProtobufJSONName(tag string) string {
+	var name string
+	for _, part := range strings.Split(tag, ",") {
+		if jsonName, ok := strings.CutPrefix(part, "json="); ok {
+			return jsonName
+		}
+		if protoName, ok := strings.CutPrefix(part, "name="); ok {
+			name = protoName
+		}
+	}
+	return name
+}
+
+// documentTag holds the tag key and namer used to name the document keys of the typed ends of the conversion, see
+// WithDocumentTag.
+type documentTag struct {
+	key   string
+	namer TagNamer
+}
+
+// name returns the document key of the field, and whether the field is part of the document at all.
+func (d documentTag) name(field reflect.StructField) (string, bool) {
+	name := ""
+	if tag, ok := field.Tag.Lookup(d.key); ok {
+		name = d.namer(tag)
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, name != SKIP_FIELD
+}
+
+// jsonName returns the key of the field in its json representation, and whether the field is part of it at all.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == SKIP_FIELD {
+		return "", false
+	}
+	if name := TagName(tag); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// renameKeys returns the json representation of a value of the given type with its keys renamed: from the json field
+// names to the document ones when toDocument is set, and the other way around otherwise. Values are renamed through
+// structs, pointers, slices, arrays and maps, while values with their own representation are left as they are.
+// Keys not declared by the struct types are dropped, and their paths appended to unknown when not nil.
+func (d documentTag) renameKeys(value any, t reflect.Type, toDocument bool, path []string, unknown *[]string) any {
+	if t == nil {
+		return value
+	}
+	t = derefType(t)
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return value
+	}
+
+	switch data := value.(type) {
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return value
+		}
+		result := make([]interface{}, len(data))
+		for i := range data {
+			result[i] = d.renameKeys(data[i], t.Elem(), toDocument, elementPath(path, i), unknown)
+		}
+		return result
+	case map[string]interface{}:
+		if t.Kind() == reflect.Map {
+			result := map[string]interface{}{}
+			for key, elem := range data {
+				result[key] = d.renameKeys(elem, t.Elem(), toDocument, keyPath(path, key), unknown)
+			}
+			return result
+		}
+		if t.Kind() == reflect.Struct {
+			result := map[string]interface{}{}
+			declared := map[string]bool{}
+			d.renameFields(data, result, t, toDocument, path, declared, unknown)
+			for key := range data {
+				if !declared[key] && unknown != nil {
+					*unknown = append(*unknown, strings.Join(keyPath(path, key), "."))
+				}
+			}
+			return result
+		}
+	}
+	return value
+}
+
+// renameFields sets into dst the values of the src map declared as fields of the struct type, renaming their keys,
+// and records the declared keys.
+// Embedded structs without json name are flattened, as encoding/json does.
+func (d documentTag) renameFields(
+	src, dst map[string]interface{},
+	t reflect.Type,
+	toDocument bool,
+	path []string,
+	declared map[string]bool,
+	unknown *[]string,
+) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		embedded := derefType(field.Type)
+		if field.Anonymous && field.Tag.Get("json") == "" && embedded.Kind() == reflect.Struct {
+			d.renameFields(src, dst, embedded, toDocument, path, declared, unknown)
+			continue
+		}
+
+		from, isJSON := jsonName(field)
+		to, isDocument := d.name(field)
+		if !field.IsExported() || !isJSON || !isDocument {
+			continue
+		}
+		if !toDocument {
+			from, to = to, from
+		}
+		declared[from] = true
+		if value, ok := src[from]; ok {
+			dst[to] = d.renameKeys(value, field.Type, toDocument, keyPath(path, from), unknown)
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package pkg
 
 import (
+	"bytes"
+	"cmp"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 )
 
 // StructEncoder is a struct that provides a way to convert an arbitrary Go struct
@@ -11,59 +18,256 @@ import (
 type StructEncoder struct {
 	src          interface{}
 	dst          interface{}
-	typeRestrain string
+	typeRestrain typeTarget
+	opts         *options
 }
 
-func (mb *StructEncoder) Init(src interface{}, dst interface{}) error {
+// Init sets the source and destination of the StructEncoder, using the type of the destination for type matching
+// unless a type name is set with AsType. The provided options are applied to the whole conversion.
+// This function returns an error if the dst interface is not a non-nil pointer.
+func (mb *StructEncoder) Init(src interface{}, dst interface{}, opts ...Option) error {
+	if err := assertNonNilPointer(dst); err != nil {
+		return errors.New("dst must be a non-nil pointer")
+	}
+	return mb.init(src, dst, newOptions(opts...))
+}
+
+func (mb *StructEncoder) init(src interface{}, dst interface{}, opts *options) error {
 	mb.src = src
 	mb.dst = dst
-	mb.typeRestrain = getTypeName(dst)
+	mb.opts = opts
+	mb.typeRestrain = targetOf(dst, mb.opts)
 	return nil
 }
 
 // Run generates a map[string]interface{} from the source object provided to the MapBuilder,
 // and then marshals that map to JSON and unmarshals it into the destination object.
 // This allows converting arbitrary Go structs into a flat map representation.
+// When using strict mode, it errors if the destination doesn't declare every field set by the source.
+// Once the destination is loaded, the AfterMarshal hook of every struct visited is called, nested structs first.
 func (mb StructEncoder) Run() error {
-	out := map[string]interface{}{}
-	if err := mb.generate(mb.src, out); err != nil {
+	out, err := mb.build()
+	if err != nil {
 		return err
 	}
+	if mb.opts.xml && isXMLType(reflect.TypeOf(mb.dst)) {
+		return mb.loadXML(out)
+	}
 
-	if outEnc, err := json.Marshal(out); err != nil {
+	var result any = out
+	if mb.opts.documentTag != nil {
+		unknown := []string{}
+		result = mb.opts.documentTag.renameKeys(out, reflect.TypeOf(mb.dst), false, nil, &unknown)
+		if mb.opts.strict && len(unknown) > 0 {
+			// the renamed document only holds the declared keys, so they can't be reported while decoding
+			slices.Sort(unknown)
+			return fmt.Errorf("%s: %s", ERROR_PATH_NOT_DECLARED, strings.Join(unknown, ", "))
+		}
+	}
+	outEnc, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(outEnc))
+	if mb.opts.strict {
+		// every path set must be declared in the destination
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&mb.dst); err != nil {
+		return err
+	}
+	return mb.afterMarshal(mb.dst)
+}
+
+// loadXML loads the xml destination with the generated document through encoding/xml, see isXMLType.
+func (mb StructEncoder) loadXML(out map[string]interface{}) error {
+	data, err := encodeXMLDocument(xmlRootName(reflect.TypeOf(mb.dst)), out, mb.opts.pathOrder)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, mb.dst); err != nil {
 		return err
-	} else {
-		return json.Unmarshal(outEnc, &mb.dst)
 	}
+	return mb.afterMarshal(mb.dst)
+}
+
+// RunMap generates the map[string]interface{} document from the source object provided to the StructEncoder,
+// without loading any destination. Once generated, the AfterMarshal hook of every struct visited is called with the
+// resulting map, nested structs first.
+func (mb StructEncoder) RunMap() (map[string]interface{}, error) {
+	out, err := mb.build()
+	if err != nil {
+		return nil, err
+	}
+	return out, mb.afterMarshal(out)
+}
+
+// build generates the map[string]interface{} document from the source object.
+func (mb StructEncoder) build() (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	if err := mb.generate(addressable(reflect.ValueOf(mb.src)), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// afterMarshal calls the AfterMarshal hook of every struct visited with the conversion result.
+func (mb StructEncoder) afterMarshal(dst interface{}) error {
+	// nested structs are visited after their parents, so call the hooks in reverse order
+	for i := len(mb.opts.marshalled) - 1; i >= 0; i-- {
+		if err := afterMarshal(mb.opts.ctx, mb.opts.marshalled[i], dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressable returns an addressable copy of the value when it is not a pointer, so hooks declared with pointer
+// receivers can be called on it without modifying the original value.
+func addressable(value reflect.Value) reflect.Value {
+	if value.Kind() == reflect.Ptr || value.CanAddr() {
+		return value
+	}
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	return ptr
 }
 
 // generate recursively traverses the src interface{} and populates the into map[string]interface{}
-// with the values from the src. It handles nested structs by either recursively calling generate
-// on them, or by flattening their fields into the into map if the DissmisNesting flag is set.
+// with the values from the src. Nested structs, and the structs held by slices, arrays and maps, are handled by
+// recursively calling generate on them, using the field path as parents path, so every value is set from the
+// document root and paths can make use of the relative and absolute path operators.
 // Any fields that are skipped (e.g. empty values) are not added to the into map.
-func (mb StructEncoder) generate(src interface{}, into map[string]interface{}) error {
-	data := reflect.ValueOf(src)
-	if data.Kind() == reflect.Ptr {
+// The BeforeMarshal hook of the src struct is called before reading its fields.
+// It errors as soon as the conversion context is done.
+func (mb StructEncoder) generate(data reflect.Value, into map[string]interface{}, parents ...string) error {
+	for data.Kind() == reflect.Ptr {
 		data = data.Elem()
 	}
-	for i := range data.NumField() {
-		field := &Field{}
-		if err := field.Init(i, data, mb.typeRestrain); err != nil {
-			return err
+	if err := mb.opts.ctx.Err(); err != nil {
+		return err
+	}
+	if err := beforeMarshal(mb.opts.ctx, data); err != nil {
+		return err
+	}
+	mb.opts.marshalled = append(mb.opts.marshalled, data)
+
+	fields, err := structFields(data, mb.typeRestrain, parents, mb.opts, false)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if mb.opts.empty == OMIT_EMPTY {
+			field.SkipIfEmpty()
 		}
-		field.SkipIfEmpty()
 		if field.Skip {
 			continue
 		}
 
-		if field.IsStruct() && field.DissmisNesting(field.Path) {
-			// if dismiss nesting then treat the child struct fields as if they
+		switch {
+		case field.IsUnion() && !field.IsNil():
+			err = mb.generateUnion(field.Value, into, field.GetPathAsParent())
+		case field.IsStruct() && !field.IsNil():
+			// when dismissing nesting the child struct fields are treated as if they
 			// were defined in the parent struct
-			mb.generate(field.Value.Interface(), into)
-		} else {
-			field.SetValueIntoMap(into)
+			err = mb.generate(field.Value, into, field.GetPathAsParent()...)
+		case field.IsStructContainer() && field.HasElements():
+			err = mb.generateElements(field.Value, into, field.Path)
+		default:
+			err = field.SetValueIntoMap(into)
 		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateUnion calls generate for the concrete value held by an interface registered as union, rooted at the
+// variant path, and sets the variant discriminator at path.
+// It errors when the concrete type is not registered as variant of the union.
+func (mb StructEncoder) generateUnion(value reflect.Value, into map[string]interface{}, path []string) error {
+	union, _ := mb.opts.types.union(value.Type())
+	name, err := union.variantOf(value.Elem().Type())
+	if err != nil {
+		return err
+	}
+	if err := union.setDiscriminator(into, path, name, mb.opts); err != nil {
+		return err
 	}
+	return mb.generate(addressable(value.Elem()), into, union.rootOf(name, path, mb.opts)...)
+}
 
+// generateElements calls generate for every struct held by the value, recursing through any combination of
+// slices, arrays, maps and pointers, or generateUnion for the interfaces registered as union. Every struct is rooted
+// at its element path, built from the container path by appending the element index for slices and arrays (eg
+// "list[0]" or "list[0][1]") and the element key for maps (eg "volumes.data"). Nil values are skipped.
+func (mb StructEncoder) generateElements(value reflect.Value, into map[string]interface{}, path []string) error {
+	if isNilValue(value) {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Interface:
+		if containsStructs(value.Type(), mb.opts) {
+			return mb.generateUnion(value, into, path)
+		}
+		return mb.generateElements(value.Elem(), into, path)
+	case reflect.Ptr:
+		return mb.generateElements(value.Elem(), into, path)
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			if err := mb.generateElements(value.Index(i), into, elementPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			key, err := encodeMapKey(iter.Key())
+			if err != nil {
+				return err
+			}
+			if err := mb.generateElements(iter.Value(), into, keyPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return mb.generate(addressable(value), into, path...)
+	}
 	return nil
 }
+
+// sortedKeys returns the keys of the document map found at path, in the order they were first set according to the
+// paths order, and sorted after them when not found.
+func sortedKeys(data map[string]interface{}, path []string, order map[string]int) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(keyOrder(order, path, a), keyOrder(order, path, b)), cmp.Compare(a, b))
+	})
+	return keys
+}
+
+// keyOrder returns the position in which the key at path was first set, or the number of paths set when it wasn't.
+func keyOrder(order map[string]int, path []string, key string) int {
+	if idx, ok := order[orderKey(append(append([]string{}, path...), key))]; ok {
+		return idx
+	}
+	return len(order)
+}
+
+// orderKey returns the key identifying the document path in the paths order, ignoring list indexes so every list
+// element shares the same order.
+func orderKey(path []string) string {
+	segments := make([]string, len(path))
+	for i, segment := range path {
+		segments[i] = pathIndexRegex.ReplaceAllString(segment, "")
+	}
+	return strings.Join(segments, "\x00")
+}
@@ -6,35 +6,242 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type Field struct {
-	tag     FieldTag
-	stfield reflect.StructField
-	Target  string
-	Value   reflect.Value
-	Kind    reflect.Kind
-	Skip    bool
-	Path    []string
+	tag       FieldTag
+	stfield   reflect.StructField
+	defaults  structDefaults
+	opts      *options
+	depth     int
+	accessors *fieldAccessors
+	// transformer referenced from the transform<> tag option, see Transformer
+	transformer *Transformer
+	// go type of the type matching target, see typeTarget
+	targetType reflect.Type
+	// nil embedded pointers the field is promoted from, to be set once the field is found, see Allocate
+	embedded []embeddedAllocation
+	Target   string
+	Value    reflect.Value
+	Kind     reflect.Kind
+	Skip     bool
+	Path     []string
+}
+
+// structDefaults holds the settings declared once for a whole struct through a blank marker field, eg
+// `_ struct{} sm:"spec.template.spec,types<A|B>"`.
+// The marker path is used as root for the path of every sibling field, and the marker types are used as default
+// type matching option for the sibling fields not declaring their own.
+type structDefaults struct {
+	root       []string
+	matchTypes []TypeMatch
+}
+
+// getStructDefaults looks for a blank marker field in the provided struct type and resolves it against the root
+// struct name, the same way any other field would be resolved.
+// When the marker uses per-type paths the matching one is used as root, so no root will be set if the root struct
+// doesn't match any of the marker types.
+func getStructDefaults(structType reflect.Type, target typeTarget, opts *options) (structDefaults, error) {
+	var defaults structDefaults
+	for i := range structType.NumField() {
+		stfield := structType.Field(i)
+		if stfield.Name != STRUCT_MARKER_NAME {
+			continue
+		}
+		tag, skip := parseTag(stfield, opts)
+		if skip {
+			continue
+		}
+
+		marker := &Field{tag: tag, stfield: stfield, Target: target.name, targetType: target.t, opts: opts}
+		if err := marker.resolvePath(); err != nil {
+			return defaults, err
+		}
+		for _, match := range tag.Opts.MatchTypes {
+			defaults.matchTypes = append(defaults.matchTypes, TypeMatch{Name: match.Name})
+		}
+		if marker.Path[0] != opts.syntax.MultiTypeName && marker.Path[0] != "" {
+			defaults.root = marker.Path
+		}
+	}
+	return defaults, nil
+}
+
+// embeddedAllocation holds a new value for a nil embedded pointer, set into the pointer once any of its promoted
+// fields is found.
+type embeddedAllocation struct {
+	ptr   reflect.Value
+	value reflect.Value
+}
+
+// structFields returns the fields of the struct value to be mapped, rooted at the parents path.
+// Untagged embedded structs are promoted, so their fields are returned as if they were declared in the struct itself.
+// Following go promotion rules, when several fields resolve to the same path the least nested one is used, and it
+// errors if there's more than one promoted field at the same depth.
+// Nil embedded pointers are skipped unless allocate is set, in which case their fields are returned bound to a new
+// value, only set into the pointer when any of those fields is found, see Allocate.
+// The fields are resolved once per struct type and bound to the value, see fieldPlans.
+func structFields(
+	structValue reflect.Value,
+	target typeTarget,
+	parents []string,
+	opts *options,
+	allocate bool,
+) ([]*Field, error) {
+	plan, err := opts.plans.structPlan(structValue.Type(), target, opts)
+	if err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	if err := plan.bind(structValue, parents, opts, allocate, 0, nil, &fields); err != nil {
+		return nil, err
+	}
+
+	byPath := map[string][]*Field{}
+	for _, field := range fields {
+		key := strings.Join(field.Path, opts.syntax.PathSeparator)
+		byPath[key] = append(byPath[key], field)
+	}
+
+	result := []*Field{}
+	for _, field := range fields {
+		candidates := byPath[strings.Join(field.Path, opts.syntax.PathSeparator)]
+		shadowed, conflict := false, false
+		for _, candidate := range candidates {
+			shadowed = shadowed || candidate.depth < field.depth
+			conflict = conflict || (candidate != field && candidate.depth == field.depth && field.depth > 0)
+		}
+		if conflict && !shadowed {
+			return nil, fmt.Errorf("%s: %s", ERROR_PROMOTED_FIELD_CONFLICT, field.stfield.Name)
+		}
+		if !shadowed {
+			result = append(result, field)
+		}
+	}
+	return result, nil
+}
+
+// IsPromoted reports whether the field is an embedded struct without tag, whose fields are promoted to the struct
+// it is embedded in.
+func (f *Field) IsPromoted() bool {
+	if !f.stfield.Anonymous || lookupTag(f.stfield, f.opts.tagKeys) != "" {
+		return false
+	}
+	t := f.stfield.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isPlainValue(t, f.opts)
 }
 
 // Configures a Field instance from the provided struct value and root struct name.
 // It parses the field tag, resolves the field path, and sets the field's Kind, Skip, and tag properties.
 // If an error occurs during field path resolution, it is returned.
 func (f *Field) Init(idx int, structValue reflect.Value, rootStruct string) (err error) {
+	err = f.resolve(idx, structValue.Type(), rootStruct)
 	f.Value = structValue.Field(idx)
+	if err != nil || f.Skip {
+		return err
+	}
+	return f.bind(structValue)
+}
+
+// resolve configures the field at the given index of the struct type, see Init. The field is not bound to any
+// value, so it can be shared by every value of the struct type, see bind.
+func (f *Field) resolve(idx int, structType reflect.Type, rootStruct string) error {
+	if f.opts == nil {
+		f.opts = newOptions()
+	}
 	f.Target = rootStruct
-	f.stfield = structValue.Type().Field(idx)
-	f.Kind = f.Value.Kind()
-	tag, skip := parseTag(f.stfield)
+	f.stfield = structType.Field(idx)
+	f.Kind = f.stfield.Type.Kind()
+
+	if f.stfield.Name == STRUCT_MARKER_NAME {
+		// struct level settings are not mapped, see getStructDefaults
+		f.Skip = true
+		return nil
+	}
+
+	tag, skip := parseTag(f.stfield, f.opts)
+	if skip && f.IsPromoted() {
+		// promoted fields are handled as if declared in the parent struct
+		tag.Path, skip = []string{f.opts.syntax.DismissNested}, false
+	}
+	if len(tag.Opts.MatchTypes) == 0 {
+		tag.Opts.MatchTypes = f.defaults.matchTypes
+	}
 	f.tag = tag
 
 	if skip {
 		f.Skip = skip
+		return nil
+	}
+
+	if err := f.resolvePath(); err != nil || f.Skip {
 		return err
 	}
+	if name := f.tag.Opts.Transformer; name != "" {
+		transformer, err := f.opts.transformers.transformer(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.stfield.Name, err)
+		}
+		f.transformer = transformer
+	}
+	f.ChRoot(f.defaults.root)
+	if f.IsAbsolute() && len(f.Path) == 1 && !f.isStructType() {
+		// only the fields of nested structs can be rooted at the document root, values need a key to be set at
+		return fmt.Errorf("%s: %s", ERROR_ROOT_PATH_NOT_STRUCT, f.stfield.Name)
+	}
+	return nil
+}
 
-	return f.resolvePath()
+// isStructType reports whether the field type is a struct whose fields should be mapped, see IsStruct.
+func (f *Field) isStructType() bool {
+	t := f.stfield.Type
+	return !isPlainValue(t, f.opts) && derefType(t).Kind() == reflect.Struct
+}
+
+// bind sets the field value from the struct value, through the getter method for fields mapped using accessors.
+func (f *Field) bind(structValue reflect.Value) error {
+	f.Value = structValue.Field(f.stfield.Index[0])
+	if !f.stfield.IsExported() && !f.IsPromoted() {
+		return f.useAccessors(structValue)
+	}
+	return nil
+}
+
+// useAccessors sets the field value from its getter method, so the setter method is used to store it back once
+// loaded, see Store.
+// It errors when accessors are not enabled, or the accessor methods can't be found.
+func (f *Field) useAccessors(structValue reflect.Value) error {
+	if !f.opts.accessors {
+		return fmt.Errorf("%s: %s", ERROR_UNEXPORTED_FIELD, f.stfield.Name)
+	}
+	accessors, err := resolveAccessors(structValue, f.stfield, f.tag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.stfield.Name, err)
+	}
+	f.accessors = accessors
+	f.Value = accessors.get()
+	return nil
+}
+
+// Allocate sets the nil embedded pointers the field is promoted from, once the field is found.
+func (f *Field) Allocate() {
+	for _, allocation := range f.embedded {
+		if allocation.ptr.IsNil() {
+			allocation.ptr.Set(allocation.value)
+		}
+	}
+}
+
+// Store writes the field value back into the struct through the setter method, for fields mapped using accessors.
+func (f *Field) Store() error {
+	if f.accessors == nil {
+		return nil
+	}
+	return f.accessors.set(f.Value)
 }
 
 // SkipIfEmpty sets the Skip field to true if the Value field is the zero value.
@@ -45,106 +252,278 @@ func (f *Field) SkipIfEmpty() {
 	}
 }
 
-// will return nil if field path should be dismissed in favour
-// of the child one
+// will return the path without the dismiss operator if field path should be dismissed in favour
+// of the child one, so the child fields are handled as if they were declared in the parent struct
 func (f *Field) GetPathAsParent() []string {
-	var path []string
-	if !f.DissmisNesting(f.Path) {
-		path = f.Path
+	if f.DissmisNesting(f.Path) {
+		return f.Path[:len(f.Path)-1]
+	}
+	return f.Path
+}
+
+// GetElementPath returns the path to the element at the given index, when the field is a slice.
+func (f *Field) GetElementPath(idx int) []string {
+	return elementPath(f.Path, idx)
+}
+
+// elementPath returns the path to the element at the given index of the list found at path, eg "list[0]", or
+// "list[0][1]" for nested lists.
+func elementPath(path []string, idx int) []string {
+	elem := append([]string{}, path...)
+	elem[len(elem)-1] = fmt.Sprintf("%s[%d]", elem[len(elem)-1], idx)
+	return elem
+}
+
+// keyPath returns the path to the value at the given key of the map found at path, eg "volumes.data".
+func keyPath(path []string, key string) []string {
+	return append(append([]string{}, path...), key)
+}
+
+// IsAbsolute reports whether the field path starts from the document root.
+func (f *Field) IsAbsolute() bool {
+	return f.Path[0] == ROOT_PATH
+}
+
+// documentPath returns the field path relative to the document root.
+func (f *Field) documentPath() []string {
+	if f.IsAbsolute() {
+		return f.Path[1:]
 	}
-	return path
+	return f.Path
 }
 
+// IsStruct reports whether the field is a struct, or a pointer or pointer chain to a struct, eg *T or **T, whose
+// fields should be mapped, even when the pointers are nil.
+// Structs handled as plain values are not considered structs, see isPlainValue.
 func (f *Field) IsStruct() bool {
-	if f.Kind == reflect.Ptr {
-		return f.Value.Elem().Kind() == reflect.Struct
-	} else {
-		return f.Kind == reflect.Struct
+	if f.transformer != nil || isPlainValue(f.Value.Type(), f.opts) {
+		return false
+	}
+	return derefType(f.Value.Type()).Kind() == reflect.Struct
+}
+
+// IsUnion reports whether the field is an interface registered as union, see RegisterUnion.
+func (f *Field) IsUnion() bool {
+	if f.transformer != nil || f.Value.Kind() != reflect.Interface {
+		return false
+	}
+	_, ok := f.opts.types.union(f.Value.Type())
+	return ok
+}
+
+// IsNil reports whether the field is a nil pointer, or a pointer chain holding a nil pointer.
+func (f *Field) IsNil() bool {
+	value := f.Value
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return true
+		}
+		value = value.Elem()
+	}
+	return false
+}
+
+// derefType returns the type pointed by the type, following pointer chains, eg **T returns T.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
+	return t
+}
+
+// IsStructContainer reports whether the field is a slice, array or map, or a pointer to one, eventually holding
+// structs whose fields should be mapped, eg []*T, [][]T, [N]T or map[string][]T, or interfaces registered as union.
+func (f *Field) IsStructContainer() bool {
+	if f.transformer != nil {
+		return false
+	}
+	t := derefType(f.Value.Type())
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return containsStructs(t, f.opts)
+	}
+	return false
+}
+
+// containsStructs reports whether values of the type are structs whose fields should be mapped, interfaces registered
+// as union, or slices, arrays, maps or pointers eventually holding them.
+func containsStructs(t reflect.Type, opts *options) bool {
+	if isPlainValue(t, opts) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsStructs(t.Elem(), opts)
+	case reflect.Interface:
+		_, isUnion := opts.types.union(t)
+		return isUnion
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// HasElements reports whether the field is a slice, array or map, or a pointer to one, holding any element.
+func (f *Field) HasElements() bool {
+	value := f.Value
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return value.Len() > 0
+	}
+	return false
 }
 
 func (f *Field) IsStructSlice() bool {
-	return f.Kind == reflect.Slice && f.Value.Type().Elem().Kind() == reflect.Struct
+	if f.Kind != reflect.Slice {
+		return false
+	}
+	elem := f.Value.Type().Elem()
+	return elem.Kind() == reflect.Struct && !isPlainValue(elem, f.opts)
 }
 
 func (f Field) DissmisNesting(path []string) bool {
-	return path[0] == DISMISS_NESTED
+	return path[len(path)-1] == f.opts.syntax.DismissNested
 }
 
 // SetValueIntoMap sets the value of the field into the provided map at the given path.
 // If the path is nil, it uses the field's Path.
 // If the path has only one element, it sets the field's value directly in the map.
 // If the path has two or more elements, it recursively sets the value in the nested map.
-func (f *Field) SetValueIntoMap(dst map[string]interface{}, path ...string) {
+// It returns the errors found while getting the field value.
+func (f *Field) SetValueIntoMap(dst map[string]interface{}, path ...string) error {
 	if path == nil {
-		path = f.Path
+		path = f.documentPath()
+		f.opts.recordPath(path)
 	}
 
-	if len(path) == 1 {
-		if f.DissmisNesting(path) {
-			dst = f.getFieldValue(f.Value).(map[string]interface{})
-		} else {
-			dst[path[0]] = f.getFieldValue(f.Value)
+	if len(path) == 1 && !f.DissmisNesting(path) {
+		value, err := f.documentValue()
+		if err != nil {
+			return err
 		}
+		dst[path[0]] = value
 	}
 	if len(path) >= 2 {
-		nested := parseNestedPath(dst, path)
+		nested := parseNestedPath(dst, path, false)
 		data := initEmptyNestedMapField(nested, dst)
-		f.SetValueIntoMap(data, path[1:]...)
+		return f.SetValueIntoMap(data, path[1:]...)
 	}
+	return nil
+}
+
+// documentValue returns the value of the field to be set into a map[string]interface{}, see getFieldValue.
+// Fields referencing a transformer are set with the value returned by it, see Transformer.
+func (f *Field) documentValue() (any, error) {
+	if f.transformer == nil || f.transformer.Marshal == nil {
+		return f.getFieldValue(f.Value)
+	}
+	value, err := f.transformer.Marshal(f.opts.ctx, f.Value.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.stfield.Name, err)
+	}
+	return value, nil
 }
 
 func initEmptyNestedMapField(nested NestedPath, from map[string]interface{}) map[string]interface{} {
-	if nested.data == nil {
-		if nested.isArray {
-			from[nested.field] = make([]interface{}, 1)
-			from[nested.field].([]interface{})[nested.idx] = map[string]interface{}{}
-			return from[nested.field].([]interface{})[nested.idx].(map[string]interface{})
-		} else {
-			from[nested.field] = map[string]interface{}{}
-			return from[nested.field].(map[string]interface{})
-		}
-	} else {
+	if nested.data != nil {
 		return nested.data
 	}
+
+	data := map[string]interface{}{}
+	if nested.isArray {
+		from[nested.field] = setIndexed(from[nested.field], nested.indices, data)
+	} else {
+		from[nested.field] = data
+	}
+	return data
+}
+
+// setIndexed sets the value at the given indices of the nested lists held by container, eg [0, 1] sets
+// container[0][1]. The lists are created or grown when needed, so elements can be set in any order.
+func setIndexed(container any, indices []int, value any) any {
+	if len(indices) == 0 {
+		return value
+	}
+	list, _ := container.([]interface{})
+	for len(list) <= indices[0] {
+		list = append(list, nil)
+	}
+	list[indices[0]] = setIndexed(list[indices[0]], indices[1:], value)
+	return list
+}
+
+// SetValue sets the value read from a map[string]interface{} into the field, see decodeValue.
+// Fields referencing a transformer are set with the value returned by it, see Transformer.
+func (f *Field) SetValue(value any) error {
+	if f.transformer != nil && f.transformer.Unmarshal != nil {
+		transformed, err := f.transformer.Unmarshal(f.opts.ctx, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.stfield.Name, err)
+		}
+		value = transformed
+	}
+	return decodeValue(f.Value, value, f.opts)
 }
 
 // GetValueFromMap retrieves the value from the provided map at the given path.
 // If the path is not provided, it defaults to the Field's Path.
 // If the path has only one element, it returns the value directly from the map.
 // If the path has two or more elements, it recursively calls GetValueFromMap on the nested data.
-// If the nested data is nil, it returns the nested data.
+// If the nested data is nil, it returns nil.
 // If the path is invalid, it panics.
 func (f *Field) GetValueFromMap(src map[string]interface{}, path ...string) any {
 	if path == nil {
-		path = f.Path
+		path = f.documentPath()
 	}
 
+	segment := f.opts.lookupKey(src, path[0])
 	if len(path) == 1 {
-		return src[path[0]]
+		return src[segment]
 	}
 	if len(path) >= 2 {
-		nested := parseNestedPath(src, path)
+		nested := parseNestedPath(src, append([]string{segment}, path[1:]...), f.opts.xmlDocument)
 		if nested.data != nil {
 			return f.GetValueFromMap(nested.data, path[1:]...)
 		} else {
-			return nested.data
+			// not returning the nil map itself, as a typed nil wouldn't compare equal to nil
+			return nil
 		}
 	}
 	panic("well well, how did we get here?")
 }
 
+// ChRoot prepends the root path to the field path, resolving the parent path operators at the start of the field
+// path against it. Absolute paths are left untouched.
 func (f *Field) ChRoot(root []string) {
-	if root != nil {
-		f.Path = append(root, f.Path...)
+	if len(root) > 0 && !f.IsAbsolute() {
+		f.Path = resolveParentPaths(append(append([]string{}, root...), f.Path...))
 	}
 }
 
+// resolveParentPaths drops the preceding segment for every parent path operator found in the given path.
+// Operators that can't be resolved because there are no preceding segments are kept, so they can be resolved
+// once the path is rooted again.
+func resolveParentPaths(path []string) []string {
+	resolved := []string{}
+	for _, segment := range path {
+		last := len(resolved) - 1
+		if segment == PARENT_PATH && last >= 0 && resolved[last] != PARENT_PATH && resolved[last] != ROOT_PATH {
+			resolved = resolved[:last]
+		} else {
+			resolved = append(resolved, segment)
+		}
+	}
+	return resolved
+}
+
 func (f *Field) resolvePath() error {
-	var err error
 	f.Path = f.tag.Path // default to tag main path
 
-	match := f.tag.findTypeMatch(f.Target)
+	match, err := f.tag.findTypeMatch(typeTarget{name: f.Target, t: f.targetType}, f.opts.types)
+	if err != nil {
+		return err
+	}
 	if match.Matches {
 		err = f.checkPerTypePathNaming(match)
 		if len(match.Path) > 0 {
@@ -156,21 +535,51 @@ func (f *Field) resolvePath() error {
 		// so set the field to be skipped
 		f.Skip = true
 	}
+	if err != nil || f.Skip {
+		return err
+	}
 
+	// name the tag path keys before expanding the variables, so their values are used as written, see WithNaming
+	f.Path = f.opts.documentKeys(f.Path)
+	return f.expandPathVars()
+}
+
+// expandPathVars replaces the variables used in the field path, eg "${name}", with the values provided
+// through the WithVars option.
+// It errors if a variable has no value set.
+func (f *Field) expandPathVars() error {
+	var err error
+	var vars map[string]string
+	if f.opts != nil {
+		vars = f.opts.vars
+	}
+
+	path := make([]string, len(f.Path))
+	for i, segment := range f.Path {
+		path[i] = pathVarRegex.ReplaceAllStringFunc(segment, func(match string) string {
+			name := pathVarRegex.FindStringSubmatch(match)[1]
+			value, ok := vars[name]
+			if !ok {
+				err = fmt.Errorf("%s: %s", ERROR_UNDEFINED_PATH_VAR, name)
+			}
+			return value
+		})
+	}
+	f.Path = path
 	return err
 }
 
 // checkPerTypePathNaming checks if the path specified in the TypeMatch
 // is valid for the current Field. It returns an error if the path is not valid.
 // The path is not valid if:
-// 1. The match has a path and the main path in the Field tag does not have the MULTI_TYPE_NAME value.
+// 1. The match has a path and the main path in the Field tag does not have the multi type name value.
 // 2. The match has a path and the main path in the Field tag has more than one element.
 func (f *Field) checkPerTypePathNaming(match TypeMatch) error {
 	var err error
 	var failedCheck bool
 
 	matchHasPath := len(match.Path) > 0
-	mainPathMatches := f.tag.Path[0] != MULTI_TYPE_NAME
+	mainPathMatches := f.tag.Path[0] != f.opts.syntax.MultiTypeName
 	mainPathMaxLen := len(f.tag.Path) > 1
 
 	if matchHasPath && mainPathMatches {
@@ -190,7 +599,9 @@ func (f *Field) checkPerTypePathNaming(match TypeMatch) error {
 
 // getFieldValue returns the value of the given field as an interface{} value.
 //
-// It handles various field types, including strings, integers, booleans, slices, maps, and structs.
+// It handles various field types, including strings, numbers, booleans, slices, maps, and structs.
+// Values implementing SMMarshaler, json.Marshaler or encoding.TextMarshaler are returned using their own
+// representation, and structs not declaring mapped fields using their json representation.
 // For slices, it recursively calls getFieldValue on each element.
 // For maps, it recursively calls getFieldValue on each value.
 // For structs, it populates a map[string]interface{} with the struct field values.
@@ -198,36 +609,61 @@ func (f *Field) checkPerTypePathNaming(match TypeMatch) error {
 //
 // field: the reflect.Value of the field to get the value from.
 // any: the value of the field.
-func (f *Field) getFieldValue(field reflect.Value) any {
+func (f *Field) getFieldValue(field reflect.Value) (any, error) {
+	if isNilValue(field) {
+		return nil, nil
+	}
+	if value, isPlain, err := marshalPlainValue(field, f.opts); isPlain {
+		return value, err
+	}
+
 	switch field.Kind() {
 	case reflect.String:
-		return field.String()
+		return field.String(), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return field.Int()
+		return field.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), nil
 	case reflect.Bool:
-		return field.Bool()
+		return field.Bool(), nil
 	case reflect.Slice:
 		list := []any{}
 		for i := range field.Len() {
-			list = append(list, f.getFieldValue(field.Index(i)))
+			value, err := f.getFieldValue(field.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
 		}
-		return list
+		return list, nil
 	case reflect.Map:
 		iter := field.MapRange()
 		result := map[string]any{}
 		for iter.Next() {
-			key := iter.Key().String()
-			value := f.getFieldValue(iter.Value())
+			key, err := encodeMapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			value, err := f.getFieldValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
 			result[key] = value
 		}
-		return result
+		return result, nil
 	case reflect.Struct:
+		if isPlainValue(field.Type(), f.opts) {
+			return jsonValue(field.Interface())
+		}
 		result := map[string]any{}
 		builder := &StructEncoder{}
-		builder.typeRestrain = f.Target
-		builder.generate(field.Interface(), result)
-		return result
-	case reflect.Ptr:
+		builder.typeRestrain = typeTarget{name: f.Target, t: f.targetType}
+		builder.opts = f.opts
+		err := builder.generate(field, result)
+		return result, err
+	case reflect.Ptr, reflect.Interface:
 		return f.getFieldValue(field.Elem())
 	default:
 		msg := fmt.Sprintf("unsupported type: %s", field.Kind().String())
@@ -235,38 +671,54 @@ func (f *Field) getFieldValue(field reflect.Value) any {
 	}
 }
 
+var (
+	pathVarRegex   = regexp.MustCompile(PATH_VAR_REGEX)
+	pathArrayRegex = regexp.MustCompile(`^([^\[]*)((?:\[[0-9]*\])+)$`)
+	pathIndexRegex = regexp.MustCompile(`\[([0-9]*)\]`)
+)
+
 type NestedPath struct {
-	idx     int
+	indices []int
 	field   string
 	isArray bool
 	data    map[string]interface{}
 }
 
-func parseNestedPath(src map[string]interface{}, path []string) NestedPath {
-	const matchArrayExp = "^(.*)\\[([0-9]*)\\]$"
-	isPathArray := regexp.MustCompile(matchArrayExp).FindStringSubmatch(path[0])
+// parseNestedPath reads the first segment of the path from src. Segments can index lists, even nested ones, eg
+// "list[0]" or "list[0][1]". Single values are read as one element lists when singleAsList is set.
+func parseNestedPath(src map[string]interface{}, path []string, singleAsList bool) NestedPath {
+	isPathArray := pathArrayRegex.FindStringSubmatch(path[0])
 	if isPathArray != nil {
-		idx, _ := strconv.Atoi(isPathArray[2])
 		fieldName := isPathArray[1]
-		var data map[string]interface{}
+		indices := []int{}
+		for _, match := range pathIndexRegex.FindAllStringSubmatch(isPathArray[2], -1) {
+			idx, _ := strconv.Atoi(match[1])
+			indices = append(indices, idx)
+		}
 
-		if src[fieldName] != nil {
-			data = src[fieldName].([]interface{})[idx].(map[string]interface{})
+		value := src[fieldName]
+		for _, idx := range indices {
+			list, ok := value.([]interface{})
+			if !ok && singleAsList && idx == 0 && value != nil {
+				// single values are handled as one element lists, as documents like xml can't tell them apart
+				continue
+			}
+			if !ok || idx >= len(list) {
+				value = nil
+				break
+			}
+			value = list[idx]
 		}
+		data, _ := value.(map[string]interface{})
 		return NestedPath{
-			idx:     idx,
+			indices: indices,
 			field:   fieldName,
 			isArray: true,
 			data:    data,
 		}
 	} else {
 		fieldName := path[0]
-		var data map[string]interface{}
-
-		srcData := src[fieldName]
-		if srcData != nil {
-			data = srcData.(map[string]interface{})
-		}
+		data, _ := src[fieldName].(map[string]interface{})
 
 		return NestedPath{
 			field: fieldName,
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"context"
+	"reflect"
+)
+
+// BeforeMarshaler is implemented by internal types needing to normalize or validate their values before being
+// marshalled. It is called for the source struct and for every nested struct and slice element before reading them.
+// When the source is not passed as a pointer the hook is called on a copy of it, but keep in mind values reachable
+// through pointers, slices or maps are still shared with the original source.
+type BeforeMarshaler interface {
+	BeforeMarshal() error
+}
+
+// BeforeMarshalerContext works like BeforeMarshaler, receiving the conversion context.
+type BeforeMarshalerContext interface {
+	BeforeMarshalContext(ctx context.Context) error
+}
+
+// AfterMarshaler is implemented by internal types needing destination specific fix-ups after being marshalled.
+// It is called for the source struct and for every nested struct and slice element once the destination is loaded,
+// receiving the conversion destination, or the generated map[string]any when marshalling to a map or json document.
+type AfterMarshaler interface {
+	AfterMarshal(dst any) error
+}
+
+// AfterMarshalerContext works like AfterMarshaler, receiving the conversion context.
+type AfterMarshalerContext interface {
+	AfterMarshalContext(ctx context.Context, dst any) error
+}
+
+// AfterUnmarshaler is implemented by internal types needing to normalize or validate their values after being
+// unmarshalled. It is called for the destination struct and for every nested struct and slice element once their
+// fields are set.
+type AfterUnmarshaler interface {
+	AfterUnmarshal() error
+}
+
+// AfterUnmarshalerContext works like AfterUnmarshaler, receiving the conversion context.
+type AfterUnmarshalerContext interface {
+	AfterUnmarshalContext(ctx context.Context) error
+}
+
+// hookReceiver returns the value hooks should be called on, preferring a pointer to the value so hooks declared with
+// pointer receivers are called as well. It returns nil if the value can't be accessed.
+func hookReceiver(value reflect.Value) any {
+	if value.CanAddr() && value.Addr().CanInterface() {
+		return value.Addr().Interface()
+	}
+	if value.IsValid() && value.CanInterface() {
+		return value.Interface()
+	}
+	return nil
+}
+
+func beforeMarshal(ctx context.Context, value reflect.Value) error {
+	switch hook := hookReceiver(value).(type) {
+	case BeforeMarshalerContext:
+		return hook.BeforeMarshalContext(ctx)
+	case BeforeMarshaler:
+		return hook.BeforeMarshal()
+	}
+	return nil
+}
+
+func afterMarshal(ctx context.Context, value reflect.Value, dst any) error {
+	switch hook := hookReceiver(value).(type) {
+	case AfterMarshalerContext:
+		return hook.AfterMarshalContext(ctx, dst)
+	case AfterMarshaler:
+		return hook.AfterMarshal(dst)
+	}
+	return nil
+}
+
+func afterUnmarshal(ctx context.Context, value reflect.Value) error {
+	switch hook := hookReceiver(value).(type) {
+	case AfterUnmarshalerContext:
+		return hook.AfterUnmarshalContext(ctx)
+	case AfterUnmarshaler:
+		return hook.AfterUnmarshal()
+	}
+	return nil
+}
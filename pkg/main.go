@@ -58,6 +58,398 @@
 //	    Name string `sm:+,types<SomeStruct:meta.name|OtherStruct:info.name>`
 //	}
 //
+// # Type Groups and Aliases
+//
+// Types can be registered under an alias or as part of a named group, so tags don't depend on the go type names.
+// Groups are referenced with the `@` prefix, and can be combined with per type paths. Registered types are matched by
+// identity, so other types sharing their name, eg from another package, don't match.
+//
+// Example:
+//
+//	pkg.RegisterTypeGroup("workloads", appsv1.Deployment{}, appsv1.StatefulSet{})
+//	pkg.RegisterTypeAlias[appsv1.Deployment]("deploy")
+//
+//	type MyStruct struct {
+//	    Name     string `sm:metadata.name,types<@workloads>`
+//	    Replicas int    `sm:+,types<deploy:spec.replicas>`
+//	}
+//
+// Types can also report the name they should be matched against by implementing the `TypeNamer` interface.
+//
+//	func (MyAPIObject) SMTypeName() string {
+//	    return "my-api-object"
+//	}
+//
+// # Struct Level Settings
+//
+// The type matching option and a root path can be declared once for all the fields of a struct, using a blank marker
+// field. Every field path will be prefixed with the marker path, and fields not declaring their own `types<>` option
+// will use the marker one.
+//
+// Keep in mind:
+//   - per type paths can be used in the marker to set a different root for each type
+//   - fields can still override the type matching option by declaring their own
+//
+// Example:
+//
+//	type MyStruct struct {
+//	    _        struct{} `sm:spec.template.spec,types<TypeOne|TypeTwo>`
+//	    Name     string   `sm:hostname`
+//	    Priority int      `sm:priority,types<TypeOne>`
+//	}
+//
+// # Path Operators
+//
+// Fields of nested structs and slice elements are resolved relative to the path they inherit, but they can still reach
+// data outside of their subtree:
+//   - `$` references the document root, eg `$.metadata.name`, and used alone roots a nested struct at it
+//   - leading dots go up one level for every extra dot, eg `..metadata.name` goes up one level and
+//     `...metadata.name` two
+//
+// When going up from a slice element, the element itself is the first level to be dismissed.
+//
+// Example:
+//
+//	type Container struct {
+//	    Image     string `sm:image`
+//	    Namespace string `sm:$.metadata.namespace`
+//	    Replicas  int    `sm:..replicas`
+//	}
+//
+//	type MyStruct struct {
+//	    Containers []Container `sm:spec.containers`
+//	}
+//
+// # Path Variables
+//
+// Paths can contain variables using the `${name}` syntax, which are replaced on each call with the values provided
+// through the `WithVars` option. Using a variable without providing its value will result in an error.
+//
+// Example:
+//
+//	type Container struct {
+//	    Image string `sm:spec.containers[${container}].image`
+//	    Value string `sm:data.${key}`
+//	}
+//
+//	sm.Marshal(src, dst, sm.WithVars(map[string]string{"container": "1", "key": "config.yaml"}))
+//
+// # Tag Profiles
+//
+// A struct can declare different mappings under different tag keys, eg to map to several API generations, and choose
+// which one to use on each call with the `WithTagKey` option. Fallback keys can be set for the fields not declaring
+// the chosen key, and fields can be excluded from a profile using `-` as tag.
+//
+// Example:
+//
+//	type MyStruct struct {
+//	    Name  string `sm:metadata.name`
+//	    Image string `sm:spec.image smv2:spec.container.image`
+//	    Flag  bool   `sm:spec.flag smv2:"-"`
+//	}
+//
+//	sm.Marshal(src, dst, sm.WithTagKey("smv2", sm.FIELD_TAG_KEY))
+//
+// # Raw Documents
+//
+// Sources don't need to be go types: `Unmarshal` also accepts a `map[string]any`, or a raw json document as `[]byte`,
+// `json.RawMessage` or `io.Reader`. As raw documents have no type name, use the `AsType` option to set the type name
+// used for type matching.
+//
+// Likewise, `MarshalMap`, `MarshalJSON` and `MarshalTo` generate the destination document from the source without
+// loading it into a go type, so payloads can be built without importing the destination types.
+// `Mapper` provides them as well, with `MarshalJSON` named `MarshalJSONDocument` so it isn't mistaken for a
+// `json.Marshaler` implementation.
+//
+// Example:
+//
+//	err := sm.Unmarshal(request.Body, &dst, sm.AsType("APIObject"))
+//	payload, err := sm.MarshalJSON(src, sm.AsType("APIObject"))
+//
+// # YAML
+//
+// `UnmarshalYAML` and `MarshalYAML` map yaml documents directly through the field paths. Values are kept as decoded
+// from the document, so yaml types like timestamps are not flattened, and the generated documents keep the source
+// fields order. Multi-document streams are supported, loading every document into a slice element when unmarshalling
+// into a slice, and generating a document for every element when marshalling a slice.
+// Both are available as `Mapper` methods too, applying the mapper options.
+//
+// Example:
+//
+//	var manifests []Deployment
+//	err := sm.UnmarshalYAML(data, &manifests, sm.AsType("Deployment"))
+//
+//	out, err := sm.MarshalYAML(manifests, "Deployment")
+//
+// # XML
+//
+// Raw xml documents can be mapped with `UnmarshalXML` and `MarshalXML`, and structs declaring an xml document, through
+// a `XMLName` field or fields with `xml` tags, are converted using `encoding/xml` when the `WithXML` option is set.
+// Paths are resolved against the elements of the document root, using `@` to reference attributes and `#text` to
+// reference the character data of elements having attributes or children. Repeated elements are handled as lists,
+// single elements can be read as one element lists, and text values are parsed into numeric and boolean fields.
+// `Mapper` provides both as `UnmarshalXMLDocument` and `MarshalXMLDocument`, applying the mapper options.
+//
+// Example:
+//
+//	type Order struct {
+//	    ID    string      `sm:"@id"`
+//	    Lines []OrderLine `sm:"items.item"`
+//	    Note  string      `sm:"note.#text"`
+//	}
+//	type OrderLine struct {
+//	    SKU      string `sm:"@sku"`
+//	    Quantity int    `sm:"quantity"`
+//	}
+//
+//	out, err := sm.MarshalXML(order, "order")
+//
+// # Document Tags
+//
+// Paths are resolved against the json names of the typed ends of the conversion. When those types name their fields
+// through another tag key, like generated yaml, bson or protobuf types, use the `WithDocumentTag` option so the paths
+// resolve against the names set by that tag instead. Untagged fields use their go field name, and fields tagged `"-"`
+// are skipped.
+//
+// The tag namer extracts the name from the tag value; `TagName` (used when nil) reads the first comma separated part,
+// and `ProtobufJSONName` reads the `json=` name of protobuf tags.
+//
+// Example:
+//
+//	err := sm.Marshal(src, &dst, sm.WithDocumentTag("yaml", nil))
+//	err := sm.Unmarshal(msg, &dst, sm.WithDocumentTag("protobuf", sm.ProtobufJSONName))
+//
+// # Naming
+//
+// Paths can be written in a naming convention different from the one of the document keys. The `WithNaming`
+// option sets the convention of the document, `SNAKE_CASE`, `CAMEL_CASE`, `KEBAB_CASE` or `SCREAMING_CASE`, and the
+// keys of the paths are converted to it, both when reading the source and when writing the destination. Keys found
+// in the documents, like the keys of map values, and the xml `@` and `#text` segments are kept as written.
+//
+// For documents not following a convention consistently, the `WithCaseInsensitiveKeys` option matches the keys ignoring
+// case when they are not found as written.
+//
+// Example:
+//
+//	type MyStruct struct {
+//	    ClientID string `sm:"spec.clientId"`
+//	}
+//
+//	// reads {"spec": {"client_id": "..."}}
+//	err := sm.Unmarshal(src, &dst, sm.WithNaming(sm.SNAKE_CASE))
+//	// reads {"spec": {"clientID": "..."}}
+//	err := sm.Unmarshal(src, &dst, sm.WithCaseInsensitiveKeys())
+//
+// # Mapper
+//
+// The behavior of the conversions can be configured through options, either per call or by creating a `Mapper` that
+// applies them to every conversion. A `Mapper` is safe for concurrent use, and the package level `Marshal` and
+// `Unmarshal` functions use a default one.
+//
+// Available options:
+//   - `WithTagKey` sets the struct tag key to read, defaults to `sm`
+//   - `WithTagSyntax` overrides the separators and operators used in tags
+//   - `WithStrict` fails the conversion when a path can't be found on the other end
+//   - `WithEmptyValues` sets whether empty values are set into the destination when marshalling
+//   - `WithTypeRegistry` sets the registry used to resolve type aliases and groups
+//   - `WithTransformers` sets the registry used to resolve the transformers referenced by fields
+//   - `WithVars` sets the values of the path variables
+//
+// Example:
+//
+//	mapper := sm.New(sm.WithTagKey("api"), sm.WithStrict())
+//	mapper.Marshal(src, dst)
+//	mapper.Unmarshal(src, dst, sm.WithVars(map[string]string{"container": "0"}))
+//
+// # Transformers
+//
+// Field values can be converted while mapped by transformers, referenced from the field tag with the `transform<>`
+// option and registered with `RegisterTransformer`, or in a `TransformerRegistry` set with the `WithTransformers`
+// option. `Marshal` returns the value written to the document, and `Unmarshal` the value loaded into the field, which
+// is then set as any other document value. A nil function leaves the value as is in that direction. Both functions
+// receive the conversion context, see `MarshalContext` and `UnmarshalContext`.
+//
+// Example:
+//
+//	sm.RegisterTransformer("cpu", sm.Transformer{
+//	    Marshal:   func(ctx context.Context, value any) (any, error) { return fmt.Sprintf("%dm", value), nil },
+//	    Unmarshal: func(ctx context.Context, value any) (any, error) {
+//	        return strconv.Atoi(strings.TrimSuffix(value.(string), "m"))
+//	    },
+//	})
+//
+//	type Container struct {
+//	    CPU int `sm:"resources.limits.cpu,transform<cpu>"`
+//	}
+//
+// # Batches
+//
+// `UnmarshalSlice` and `MarshalSlice` convert every element of a slice into the element at the same index of the
+// destination slice, and `UnmarshalMapValues` and `MarshalMapValues` every value of a map into the destination map at
+// the same key. The options, and the fields of every struct type, are resolved once for the whole batch and reused for
+// every element, and the `WithWorkers` option spreads the elements over a bounded number of concurrent workers.
+//
+// Every element is converted even when others fail, the failed ones are reported by index, or key, in a `BatchError`.
+// The `Context` variants stop the batch as soon as the context is done.
+//
+// Example:
+//
+//	var workloads []*Workload
+//	err := sm.UnmarshalSlice(deployments, &workloads, sm.WithWorkers(8))
+//
+//	var batchErr *sm.BatchError
+//	if errors.As(err, &batchErr) {
+//	    for idx, err := range batchErr.Errors { ... }
+//	}
+//
+// # Typed Conversions
+//
+// `Convert` returns a new value of the given type loaded from the source. The destination is unmarshalled when its type
+// declares mapped fields, and marshalled from the source paths otherwise. For repeated conversions between the same
+// types, `NewMapper` returns a typed mapper whose `To` and `From` methods convert in both directions, resolving the
+// options and the fields of the mapped struct once for every conversion. Destination types can be pointers, in which
+// case a new value is allocated.
+//
+// Example:
+//
+//	workload, err := sm.Convert[*Workload](deployment)
+//
+//	mapper := sm.NewMapper[Workload, appsv1.Deployment](sm.WithStrict())
+//	deployment, err := mapper.To(workload)
+//	workload, err := mapper.From(deployment)
+//
+// # Context
+//
+// `MarshalContext` and `UnmarshalContext` take a context, stopping the conversion as soon as the context is done.
+// The context is checked before reading every struct, nested structs and slice elements included, and is passed down
+// to the hooks and to the transformers, which can stop the conversion by returning the context error.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//	err := sm.UnmarshalContext(ctx, src, dst)
+//
+// # Hooks
+//
+// Internal types can implement hook interfaces to keep their normalization and validation logic next to the type.
+// Hooks are called for the root struct, and for every nested struct and slice element visited during the conversion.
+//
+//   - `BeforeMarshal() error` is called before reading the struct fields when marshalling
+//   - `AfterMarshal(dst any) error` is called once the destination is loaded, receiving the destination
+//   - `AfterUnmarshal() error` is called once the struct fields are set when unmarshalling
+//
+// Every hook has a context-aware variant receiving the conversion context, eg `BeforeMarshalContext(ctx) error`.
+//
+// Example:
+//
+//	func (s *MyStruct) AfterUnmarshal() error {
+//	    if s.Name == "" {
+//	        return errors.New("name is required")
+//	    }
+//	    return nil
+//	}
+//
+// # Custom Marshaling
+//
+// Types can control their own representation by implementing `SMMarshaler` and `SMUnmarshaler`, in which case their
+// fields won't be mapped. The value returned by `MarshalSM` is set at the field path as is, and `UnmarshalSM` receives
+// the raw value read from the field path. Slices and maps of these types are handled element by element.
+//
+// Types implementing `json.Marshaler` or `encoding.TextMarshaler` (and their unmarshaling counterparts), like
+// `time.Time` or `net.IP`, are mapped using their standard representation as well, unless they are structs declaring
+// mapped fields, whose fields are still mapped.
+//
+// Example:
+//
+//	type ResourceID struct {
+//	    kind string
+//	    name string
+//	}
+//
+//	func (id ResourceID) MarshalSM() (any, error) {
+//	    return id.kind + "/" + id.name, nil
+//	}
+//
+//	func (id *ResourceID) UnmarshalSM(value any) error {
+//	    raw, _ := value.(string)
+//	    id.kind, id.name, _ = strings.Cut(raw, "/")
+//	    return nil
+//	}
+//
+// # Unions
+//
+// Fields typed as an interface can be mapped by registering a union for the interface with `RegisterUnion`, or in a
+// custom registry with `TypeRegistry.RegisterUnion`. The union lists the concrete struct types implementing the
+// interface, and the concrete type is chosen by its discriminator when unmarshalling, then mapped using its own tags.
+// When marshalling the concrete type is mapped the same way, setting its discriminator. Slices, arrays and maps of
+// the interface are mapped element by element, so every element can hold a different concrete type.
+//
+// The discriminator can be a value found at a path relative to the field path, eg `type`, or the key present at the
+// field path when no `Discriminator` is set, in which case the concrete type fields are rooted at that key.
+//
+// Example:
+//
+//	type Trigger interface{ isTrigger() }
+//
+//	type CronTrigger struct {
+//	    Schedule string `sm:"schedule"`
+//	}
+//	type WebhookTrigger struct {
+//	    URL string `sm:"url"`
+//	}
+//
+//	type Pipeline struct {
+//	    Trigger Trigger `sm:"spec.trigger"`
+//	}
+//
+//	sm.RegisterUnion[Trigger](sm.Union{
+//	    Discriminator: "type",
+//	    Variants:      map[string]any{"cron": CronTrigger{}, "webhook": WebhookTrigger{}},
+//	})
+//
+// # Embedded Structs
+//
+// Embedded structs without tag are promoted, so their fields are mapped as if they were declared in the parent struct,
+// same as using `->` as field name. Use `sm:"-"` to exclude an embedded struct, or tag it with a path to handle it as a
+// regular nested field.
+//
+// When several fields resolve to the same path the least nested one is used, and an error is returned if more than one
+// promoted field resolves to it at the same depth.
+//
+// Example:
+//
+//	type ObjectMeta struct {
+//	    Name string `sm:"metadata.name"`
+//	}
+//	type Spec struct {
+//	    Replicas int `sm:"spec.replicas"`
+//	}
+//
+//	type Workload struct {
+//	    ObjectMeta
+//	    *Spec
+//	}
+//
+// # Unexported Fields
+//
+// Unexported fields can't be mapped, so tagging them makes the conversion fail. Use the `WithAccessors` option to map
+// them through their getter and setter methods instead, so structs can keep their invariants private. By default a
+// field named `name` is read with `Name()` and written with `SetName(value)`, which can optionally return an error.
+// The method names can be set per field using the `get<>` and `set<>` tag options.
+//
+// Example:
+//
+//	type Workload struct {
+//	    name     string `sm:"metadata.name"`
+//	    replicas int    `sm:"spec.replicas,get<GetReplicas>,set<Scale>"`
+//	}
+//
+//	func (w *Workload) Name() string            { return w.name }
+//	func (w *Workload) SetName(name string)     { w.name = name }
+//	func (w *Workload) GetReplicas() int        { return w.replicas }
+//	func (w *Workload) Scale(replicas int) error { ... }
+//
+//	err := sm.Unmarshal(src, &workload, sm.WithAccessors())
+//
 // # Nesting
 //
 // By default fields that are structs will inherit the parent path, but you can dismiss this by using the `->` operator
@@ -76,15 +468,27 @@
 //	    Child1 PreservedParent `sm:some.path.to.use`
 //	    Child2 DismissParent `->`
 //	}
+//
+// Structs held by slices, arrays and maps, in any combination and through pointers, eg `[]*T`, `[][]T`, `[N]T` or
+// `map[string][]T`, are mapped element by element, the fields of every element being rooted at the element path: the
+// element index for slices and arrays, eg `volumes[0]` or `matrix[0][1]`, and the element key for maps, eg
+// `volumes.data`.
+//
+// When unmarshalling, nil pointers to structs, even pointer chains like `**T`, are only allocated when any of the
+// nested fields paths is present in the source, so absent subtrees are left nil.
 package pkg
 
 import (
+	"context"
+	"io"
 	"reflect"
 )
 
 const (
 	// field tag to parse
 	FIELD_TAG_KEY = "sm"
+	// field tag value used to exclude a field from the mapping, eg sm:"-"
+	SKIP_FIELD = "-"
 	// type separator when encoding to multiple types from a single source, eg sm:"example,types<Struct1|Struct2>"
 	TYPES_SPLIT = "|"
 	// type path separator when setting per type path, eg sm:"+,types<Struct1:path.one|Struct2:path.name>"
@@ -93,40 +497,106 @@ const (
 	DISMISS_NESTED = "->"
 	// path name to be used when setting per type path, eg sm:"+,types<Struct1:path.one|Struct2:path.name>"
 	MULTI_TYPE_NAME = "+"
+	// separator between the segments of a field path, eg sm:"metadata.name"
+	PATH_SEPARATOR = "."
+	// path segment referencing the document root, eg sm:"$.metadata.name"
+	ROOT_PATH = "$"
+	// path segment referencing the parent path, written as leading dots in the tag, eg sm:"..metadata.name"
+	PARENT_PATH = ".."
+	// field name used to declare struct level settings, eg _ struct{} `sm:"spec.template,types<Struct1|Struct2>"`
+	STRUCT_MARKER_NAME = "_"
+	// prefix used to reference a registered type group in the type matching option, eg sm:"example,types<@group>"
+	TYPE_GROUP_PREFIX = "@"
+	// prefix of the path segments referencing xml attributes, eg sm:"item.@id"
+	XML_ATTR_PREFIX = "@"
+	// path segment referencing the character data of xml elements having attributes or children, eg sm:"item.#text"
+	XML_TEXT_KEY = "#text"
 
 	ERROR_PER_TYPE_PATH_IS_NOT_VALID = "main path should be '+' when using per-type path matching"
+	ERROR_UNKNOWN_TYPE_GROUP         = "type group is not registered"
+	ERROR_PATH_OUT_OF_ROOT           = "path goes up beyond the document root"
+	ERROR_ROOT_PATH_NOT_STRUCT       = "only struct fields can be mapped to the document root"
+	ERROR_UNDEFINED_PATH_VAR         = "path variable has no value set"
+	ERROR_PATH_NOT_FOUND             = "path not found in source"
+	ERROR_PATH_NOT_DECLARED          = "path not declared in destination"
+	ERROR_PROMOTED_FIELD_CONFLICT    = "promoted fields resolve to the same path at the same depth"
+	ERROR_UNEXPORTED_FIELD           = "unexported fields can't be mapped"
+	ERROR_INVALID_ACCESSOR           = "accessor method not found or has an invalid signature"
+	ERROR_UNKNOWN_TRANSFORMER        = "transformer is not registered"
+	ERROR_UNKNOWN_UNION_VARIANT      = "discriminator value is not registered as union variant"
+	ERROR_UNKNOWN_UNION_TYPE         = "type is not registered as union variant"
+	ERROR_INVALID_DOCUMENT           = "document root must be a mapping"
+	ERROR_INVALID_SLICE_BATCH        = "source must be a slice or array, and destination a non-nil pointer to a slice"
+	ERROR_INVALID_MAP_BATCH          = "source must be a map, and destination a non-nil pointer to a map of its key type"
+	ERROR_BATCH_FAILED               = "batch elements failed to convert"
 
-	TYPE_OPTS_REGEX = `^types<([^>]+)>$`
+	TYPE_OPTS_REGEX      = `^types<([^>]+)>$`
+	GETTER_OPTS_REGEX    = `^get<([^>]+)>$`
+	SETTER_OPTS_REGEX    = `^set<([^>]+)>$`
+	TRANSFORM_OPTS_REGEX = `^transform<([^>]+)>$`
+	PATH_VAR_REGEX       = `\$\{([^}]+)\}`
 )
 
-func getTypeName(t interface{}) string {
-	val := reflect.ValueOf(t)
+// getTypeNameOf returns the name used to evaluate type matching options for the given type.
+// Types implementing TypeNamer report their own name, otherwise the go type name is used.
+func getTypeNameOf(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
 
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+	if namer, ok := reflect.New(t).Interface().(TypeNamer); ok {
+		return namer.SMTypeName()
 	}
 
-	return val.Type().Name()
+	return t.Name()
 }
 
 // Unmarshal marshals the given source and then unmarshals into the jsonpath compatible destination.
 // This function is intended to convert between the provided API object and the system internal definitions.
-func Unmarshal(src interface{}, dst interface{}) (err error) {
-	decoder := &StructDecoder{}
-	if err := decoder.Init(src, dst); err != nil {
-		return err
-	}
-
-	return decoder.Run()
+// The source can also be a map[string]any or a raw json document, as []byte, json.RawMessage or io.Reader, in which
+// case the AsType option sets the type name used for type matching.
+// The provided options are applied to the whole conversion.
+func Unmarshal(src interface{}, dst interface{}, opts ...Option) (err error) {
+	return defaultMapper.Unmarshal(src, dst, opts...)
 }
 
 // Marshal marshals the given jsonpath compatible source to a JSON byte slice,
 // and then unmarshals it into the given destination interface{}.
 // This function is intended to convert between system internal definitions and the destined API object.
-func Marshal(src interface{}, dst interface{}) error {
-	encoder := &StructEncoder{}
-	if err := encoder.Init(src, dst); err != nil {
-		return err
-	}
-	return encoder.Run()
+// The destination must be a non-nil pointer, see Convert for a type-safe alternative.
+// The provided options are applied to the whole conversion.
+func Marshal(src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.Marshal(src, dst, opts...)
+}
+
+// MarshalMap returns the document generated from the given jsonpath compatible source, instead of loading it into a
+// destination, so payloads can be built without importing the destination types.
+// As there's no destination type, use the AsType option to set the type name used for type matching.
+func MarshalMap(src interface{}, opts ...Option) (map[string]any, error) {
+	return defaultMapper.MarshalMap(src, opts...)
+}
+
+// MarshalJSON returns the json document generated from the given jsonpath compatible source, see MarshalMap.
+func MarshalJSON(src interface{}, opts ...Option) ([]byte, error) {
+	return defaultMapper.MarshalJSONDocument(src, opts...)
+}
+
+// MarshalTo writes the json document generated from the given jsonpath compatible source to the writer, see
+// MarshalMap.
+func MarshalTo(w io.Writer, src interface{}, opts ...Option) error {
+	return defaultMapper.MarshalTo(w, src, opts...)
+}
+
+// UnmarshalContext works like Unmarshal, but stops the conversion as soon as the context is done.
+// The context is checked before reading every struct, nested structs and slice elements included, and is passed
+// down to the hooks and transformers called during the conversion.
+func UnmarshalContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalContext(ctx, src, dst, opts...)
+}
+
+// MarshalContext works like Marshal, but stops the conversion as soon as the context is done.
+// The context is checked before reading every struct, nested structs and slice elements included, and is passed
+// down to the hooks and transformers called during the conversion.
+func MarshalContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return defaultMapper.MarshalContext(ctx, src, dst, opts...)
 }
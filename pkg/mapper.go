@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// Mapper converts between structs applying the options it was created with to every conversion.
+// It is safe for concurrent use, so a single instance can be shared for every conversion with the same settings.
+type Mapper struct {
+	opts []Option
+}
+
+// New returns a Mapper applying the provided options to every conversion.
+func New(opts ...Option) *Mapper {
+	return &Mapper{opts: append([]Option{}, opts...)}
+}
+
+// Unmarshal loads the jsonpath compatible destination with the values of the given source, see Unmarshal.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) Unmarshal(src interface{}, dst interface{}, opts ...Option) error {
+	decoder := &StructDecoder{}
+	if err := decoder.Init(src, dst, m.withOptions(opts)...); err != nil {
+		return err
+	}
+
+	return decoder.Run()
+}
+
+// Marshal loads the given destination with the values of the jsonpath compatible source, see Marshal.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) Marshal(src interface{}, dst interface{}, opts ...Option) error {
+	encoder := &StructEncoder{}
+	if err := encoder.Init(src, dst, m.withOptions(opts)...); err != nil {
+		return err
+	}
+	return encoder.Run()
+}
+
+// MarshalMap returns the document generated from the jsonpath compatible source, see MarshalMap.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalMap(src interface{}, opts ...Option) (map[string]any, error) {
+	encoder := &StructEncoder{}
+	if err := encoder.init(src, nil, newOptions(m.withOptions(opts)...)); err != nil {
+		return nil, err
+	}
+	return encoder.RunMap()
+}
+
+// MarshalJSONDocument returns the json document generated from the jsonpath compatible source, see MarshalJSON.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalJSONDocument(src interface{}, opts ...Option) ([]byte, error) {
+	out, err := m.MarshalMap(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// MarshalTo writes the json document generated from the jsonpath compatible source to the writer, see MarshalTo.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalTo(w io.Writer, src interface{}, opts ...Option) error {
+	out, err := m.MarshalMap(src, opts...)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+// UnmarshalContext works like Unmarshal, but stops the conversion as soon as the context is done.
+// The context is checked before reading every struct, nested structs and slice elements included, and is passed
+// down to the hooks and transformers called during the conversion.
+func (m *Mapper) UnmarshalContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return m.Unmarshal(src, dst, append(append([]Option{}, opts...), withContext(ctx))...)
+}
+
+// MarshalContext works like Marshal, but stops the conversion as soon as the context is done.
+// The context is checked before reading every struct, nested structs and slice elements included, and is passed
+// down to the hooks and transformers called during the conversion.
+func (m *Mapper) MarshalContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return m.Marshal(src, dst, append(append([]Option{}, opts...), withContext(ctx))...)
+}
+
+// UnmarshalSlice loads every element of the destination slice with the values of the element of the source slice or
+// array at the same index, see UnmarshalSlice.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) UnmarshalSlice(src interface{}, dst interface{}, opts ...Option) error {
+	b, err := sliceBatch(src, dst)
+	if err != nil {
+		return err
+	}
+	return runBatch(b, newOptions(m.withOptions(opts)...), decodeWith)
+}
+
+// MarshalSlice loads every element of the destination slice with the values of the element of the source slice or
+// array at the same index, see MarshalSlice.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalSlice(src interface{}, dst interface{}, opts ...Option) error {
+	b, err := sliceBatch(src, dst)
+	if err != nil {
+		return err
+	}
+	return runBatch(b, newOptions(m.withOptions(opts)...), encodeWith)
+}
+
+// UnmarshalMapValues loads the destination map with every value of the source map, converted at the same key, see
+// UnmarshalMapValues.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) UnmarshalMapValues(src interface{}, dst interface{}, opts ...Option) error {
+	b, err := mapBatch(src, dst)
+	if err != nil {
+		return err
+	}
+	return runBatch(b, newOptions(m.withOptions(opts)...), decodeWith)
+}
+
+// MarshalMapValues loads the destination map with every value of the source map, converted at the same key, see
+// MarshalMapValues.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalMapValues(src interface{}, dst interface{}, opts ...Option) error {
+	b, err := mapBatch(src, dst)
+	if err != nil {
+		return err
+	}
+	return runBatch(b, newOptions(m.withOptions(opts)...), encodeWith)
+}
+
+// UnmarshalSliceContext works like UnmarshalSlice, but stops the conversion as soon as the context is done.
+func (m *Mapper) UnmarshalSliceContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return m.UnmarshalSlice(src, dst, append(append([]Option{}, opts...), withContext(ctx))...)
+}
+
+// MarshalSliceContext works like MarshalSlice, but stops the conversion as soon as the context is done.
+func (m *Mapper) MarshalSliceContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return m.MarshalSlice(src, dst, append(append([]Option{}, opts...), withContext(ctx))...)
+}
+
+// UnmarshalMapValuesContext works like UnmarshalMapValues, but stops the conversion as soon as the context is done.
+func (m *Mapper) UnmarshalMapValuesContext(
+	ctx context.Context,
+	src interface{},
+	dst interface{},
+	opts ...Option,
+) error {
+	return m.UnmarshalMapValues(src, dst, append(append([]Option{}, opts...), withContext(ctx))...)
+}
+
+// MarshalMapValuesContext works like MarshalMapValues, but stops the conversion as soon as the context is done.
+func (m *Mapper) MarshalMapValuesContext(ctx context.Context, src interface{}, dst interface{}, opts ...Option) error {
+	return m.MarshalMapValues(src, dst, append(append([]Option{}, opts...), withContext(ctx))...)
+}
+
+func (m *Mapper) withOptions(opts []Option) []Option {
+	return append(append([]Option{}, m.opts...), opts...)
+}
+
+var defaultMapper = New()
@@ -0,0 +1,307 @@
+package pkg
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// SMMarshaler is implemented by types controlling their own representation when marshalled.
+// The value returned by MarshalSM is set at the field path as is, instead of mapping the type fields.
+type SMMarshaler interface {
+	MarshalSM() (any, error)
+}
+
+// SMUnmarshaler is implemented by types controlling how they are loaded when unmarshalled.
+// UnmarshalSM receives the raw value read from the field path, instead of mapping the type fields.
+type SMUnmarshaler interface {
+	UnmarshalSM(value any) error
+}
+
+var (
+	smMarshalerType     = reflect.TypeFor[SMMarshaler]()
+	smUnmarshalerType   = reflect.TypeFor[SMUnmarshaler]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+)
+
+// hasCustomMarshaling reports whether the type, or a pointer to it, implements SMMarshaler or SMUnmarshaler.
+func hasCustomMarshaling(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PointerTo(t)
+	}
+	return t.Implements(smMarshalerType) || t.Implements(smUnmarshalerType)
+}
+
+// isPlainValue reports whether values of the type are handled as a whole instead of mapping their fields.
+// That is the case for types implementing any of the SM marshaling interfaces, and for types implementing any of the
+// json or text marshaling interfaces unless they are structs declaring fields under the conversion tag keys.
+func isPlainValue(t reflect.Type, opts *options) bool {
+	t = derefType(t)
+	ptr := reflect.PointerTo(t)
+	if ptr.Implements(smMarshalerType) || ptr.Implements(smUnmarshalerType) {
+		return true
+	}
+	if isMappedStruct(t, opts) {
+		return false
+	}
+	for _, iface := range []reflect.Type{
+		jsonMarshalerType, jsonUnmarshalerType,
+		textMarshalerType, textUnmarshalerType,
+	} {
+		if ptr.Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMappedStruct reports whether the type is a struct declaring fields under the conversion tag keys, whose fields
+// are mapped even when it implements the json or text marshaling interfaces.
+func isMappedStruct(t reflect.Type, opts *options) bool {
+	t = derefType(t)
+	return t.Kind() == reflect.Struct && declaresMappedFields(t, opts.tagKeys)
+}
+
+// declaresMappedFields reports whether any field of the struct type declares one of the tag keys, including the
+// fields promoted from embedded structs.
+func declaresMappedFields(t reflect.Type, keys []string) bool {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if lookupTag(field, keys) != "" {
+			return true
+		}
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && embedded.Kind() == reflect.Struct && declaresMappedFields(embedded, keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNilValue reports whether the value is invalid or a nil pointer or interface.
+func isNilValue(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// asInterface returns the implementation of the interface T by the value, using a pointer to the value when needed.
+func asInterface[T any](value reflect.Value) (T, bool) {
+	if value.CanInterface() {
+		if impl, ok := value.Interface().(T); ok {
+			return impl, true
+		}
+	}
+	if value.CanAddr() && value.Addr().CanInterface() {
+		impl, ok := value.Addr().Interface().(T)
+		return impl, ok
+	}
+	var impl T
+	return impl, false
+}
+
+// marshalPlainValue returns the representation of values implementing SMMarshaler, json.Marshaler or
+// encoding.TextMarshaler, in that order of precedence. Timestamps are returned as they are. The json and text
+// representations are not used for structs declaring mapped fields, see isPlainValue.
+// The last return value reports whether the value is represented through any of them.
+func marshalPlainValue(value reflect.Value, opts *options) (any, bool, error) {
+	if value.Type() == timeType {
+		// kept as is, so document formats supporting timestamps don't get them as strings
+		return value.Interface(), true, nil
+	}
+	if marshaler, ok := asInterface[SMMarshaler](value); ok {
+		result, err := marshaler.MarshalSM()
+		return result, true, err
+	}
+	if isMappedStruct(value.Type(), opts) {
+		return nil, false, nil
+	}
+	if marshaler, ok := asInterface[json.Marshaler](value); ok {
+		data, err := marshaler.MarshalJSON()
+		if err != nil {
+			return nil, true, err
+		}
+		var result any
+		return result, true, json.Unmarshal(data, &result)
+	}
+	if marshaler, ok := asInterface[encoding.TextMarshaler](value); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), true, err
+	}
+	return nil, false, nil
+}
+
+// jsonValue returns the value as decoded from its json representation.
+func jsonValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	return result, json.Unmarshal(data, &result)
+}
+
+// encodeMapKey returns the string representation of a map key, using encoding.TextMarshaler when implemented.
+func encodeMapKey(key reflect.Value) (string, error) {
+	if marshaler, ok := asInterface[encoding.TextMarshaler](key); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	return fmt.Sprint(key.Interface()), nil
+}
+
+// asUnmarshaler returns the SMUnmarshaler implementation of the settable value, allocating nil pointers or using a
+// pointer to the value when needed.
+func asUnmarshaler(value reflect.Value) (SMUnmarshaler, bool) {
+	if value.Kind() == reflect.Ptr && value.Type().Implements(smUnmarshalerType) {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		unmarshaler, ok := value.Interface().(SMUnmarshaler)
+		return unmarshaler, ok
+	}
+	if value.CanAddr() && reflect.PointerTo(value.Type()).Implements(smUnmarshalerType) {
+		unmarshaler, ok := value.Addr().Interface().(SMUnmarshaler)
+		return unmarshaler, ok
+	}
+	return nil, false
+}
+
+// decodeValue sets the raw value read from a map[string]interface{} into the settable target.
+// Targets implementing SMUnmarshaler receive the raw value, as well as the elements of slices and maps of them, whose
+// elements are decoded one by one, as the ones of xml documents.
+// Values assignable to the target are set as is, text values of xml documents are parsed into numeric and boolean
+// targets, or pointers to them, and any other value is converted to the target type through its json representation.
+func decodeValue(target reflect.Value, value any, opts *options) error {
+	if unmarshaler, ok := asUnmarshaler(target); ok {
+		return unmarshaler.UnmarshalSM(value)
+	}
+
+	list, isList := asList(value, target.Type(), opts)
+	if isList && target.Kind() == reflect.Slice && (opts.xmlDocument || hasCustomMarshaling(target.Type().Elem())) {
+		slice := reflect.MakeSlice(target.Type(), len(list), len(list))
+		for i := range list {
+			if err := decodeValue(slice.Index(i), list[i], opts); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	}
+
+	data, isMap := value.(map[string]interface{})
+	if isMap && target.Kind() == reflect.Map && (opts.xmlDocument || hasCustomMarshaling(target.Type().Elem())) {
+		return decodeMapValues(target, data, opts)
+	}
+
+	if value != nil && reflect.TypeOf(value).AssignableTo(target.Type()) {
+		// values already holding the target type, like timestamps decoded from yaml documents
+		target.Set(reflect.ValueOf(value))
+		return nil
+	}
+	if text, isText := value.(string); isText && opts.xmlDocument && isScalarKind(derefType(target.Type()).Kind()) {
+		// xml documents hold every value as text, empty elements holding the zero value
+		if text == "" {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		return json.Unmarshal([]byte(text), target.Addr().Interface())
+	}
+	if isList {
+		value = list
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, target.Addr().Interface())
+}
+
+// asList returns the value as list when decoding it into the target type.
+// Single values of xml documents are returned as one element lists for slice and array targets, as xml can't tell
+// apart lists of a single element.
+func asList(value any, target reflect.Type, opts *options) ([]interface{}, bool) {
+	if list, isList := value.([]interface{}); isList {
+		return list, true
+	}
+	kind := target.Kind()
+	if value == nil || !opts.xmlDocument {
+		return nil, false
+	}
+	if (kind != reflect.Slice && kind != reflect.Array) || target.Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	elem := derefType(target.Elem()).Kind()
+	if _, isText := value.(string); isText && (elem == reflect.String || isScalarKind(elem)) {
+		return []interface{}{value}, true
+	}
+	if _, isMap := value.(map[string]interface{}); isMap {
+		return []interface{}{value}, true
+	}
+	return nil, false
+}
+
+// isScalarKind reports whether the kind is numeric or boolean.
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// decodeMapValues sets every value of the data map into the target map, decoding the values through decodeValue.
+func decodeMapValues(target reflect.Value, data map[string]interface{}, opts *options) error {
+	mapType := target.Type()
+	result := reflect.MakeMapWithSize(mapType, len(data))
+	for key, raw := range data {
+		mapKey, err := decodeMapKey(mapType.Key(), key)
+		if err != nil {
+			return err
+		}
+		mapValue := reflect.New(mapType.Elem()).Elem()
+		if err := decodeValue(mapValue, raw, opts); err != nil {
+			return err
+		}
+		result.SetMapIndex(mapKey, mapValue)
+	}
+	target.Set(result)
+	return nil
+}
+
+// decodeMapKey converts a map[string]interface{} key to the given map key type.
+// Key types implementing encoding.TextUnmarshaler are decoded from the key text, string keys are converted directly,
+// and any other key type is decoded through its json representation.
+func decodeMapKey(keyType reflect.Type, key string) (reflect.Value, error) {
+	mapKey := reflect.New(keyType).Elem()
+	if unmarshaler, ok := asInterface[encoding.TextUnmarshaler](mapKey); ok {
+		return mapKey, unmarshaler.UnmarshalText([]byte(key))
+	}
+	if keyType.Kind() == reflect.String {
+		mapKey.SetString(key)
+		return mapKey, nil
+	}
+	err := json.Unmarshal([]byte(key), mapKey.Addr().Interface())
+	return mapKey, err
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamingStrategy sets the naming convention of the document keys, see WithNaming.
+type NamingStrategy int
+
+const (
+	// keys are named as written in the field paths
+	AS_WRITTEN NamingStrategy = iota
+	// keys are named like client_id
+	SNAKE_CASE
+	// keys are named like clientId
+	CAMEL_CASE
+	// keys are named like client-id
+	KEBAB_CASE
+	// keys are named like CLIENT_ID
+	SCREAMING_CASE
+)
+
+// Apply returns the name written in the naming convention, eg SNAKE_CASE.Apply("clientID") returns "client_id".
+// Names are split into words at underscores, dashes, spaces and case changes, names without words are returned as is.
+func (s NamingStrategy) Apply(name string) string {
+	words := splitWords(name)
+	if s == AS_WRITTEN || len(words) == 0 {
+		return name
+	}
+	for i, word := range words {
+		switch {
+		case s == SCREAMING_CASE:
+			words[i] = strings.ToUpper(word)
+		case s == CAMEL_CASE && i > 0:
+			first, size := utf8.DecodeRuneInString(word)
+			words[i] = string(unicode.ToUpper(first)) + strings.ToLower(word[size:])
+		default:
+			words[i] = strings.ToLower(word)
+		}
+	}
+	switch s {
+	case CAMEL_CASE:
+		return strings.Join(words, "")
+	case KEBAB_CASE:
+		return strings.Join(words, "-")
+	default:
+		return strings.Join(words, "_")
+	}
+}
+
+// splitWords splits the name into its words, eg "HTTPServerID" returns "HTTP", "Server" and "ID".
+func splitWords(name string) []string {
+	words := []string{}
+	runes := []rune(name)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+			}
+			start = -1
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) && isWordBoundary(runes, i) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// isWordBoundary reports whether the upper case rune at i starts a word: it follows a lower case letter or a digit,
+// eg "clientId", or it ends an upper case run followed by a lower case letter, eg "HTTPServer".
+func isWordBoundary(runes []rune, i int) bool {
+	prev := runes[i-1]
+	if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+}
+
+// splitSegment splits the path segment into its key and list indexes, eg "list[0][1]" returns "list" and "[0][1]".
+func splitSegment(segment string) (string, string) {
+	if idx := strings.Index(segment, "["); idx >= 0 {
+		return segment[:idx], segment[idx:]
+	}
+	return segment, ""
+}
+
+// documentKey returns the path segment with its key written in the naming strategy of the document.
+// Path operators, xml attributes and text, and segments using path variables are returned as written.
+func (o *options) documentKey(segment string) string {
+	if o.naming == AS_WRITTEN || segment == o.syntax.DismissNested || segment == PARENT_PATH {
+		return segment
+	}
+	if strings.HasPrefix(segment, XML_ATTR_PREFIX) || segment == XML_TEXT_KEY || pathVarRegex.MatchString(segment) {
+		return segment
+	}
+	key, indexes := splitSegment(segment)
+	return o.naming.Apply(key) + indexes
+}
+
+// documentKeys returns the path written in the naming strategy of the document, see documentKey.
+// Only the paths declared by tags and registrations are named, so keys taken from the documents, like the keys of
+// map values, are kept as found.
+func (o *options) documentKeys(path []string) []string {
+	if o.naming == AS_WRITTEN {
+		return path
+	}
+	keys := make([]string, len(path))
+	for i, segment := range path {
+		keys[i] = o.documentKey(segment)
+	}
+	return keys
+}
+
+// lookupKey returns the path segment with its key replaced by the matching key of src. Keys are matched exactly, or
+// ignoring case when the key is not found and WithCaseInsensitiveKeys is set, the first one in order when many match.
+func (o *options) lookupKey(src map[string]interface{}, segment string) string {
+	key, indexes := splitSegment(segment)
+	if _, ok := src[key]; ok || !o.caseInsensitive {
+		return segment
+	}
+	keys := make([]string, 0, len(src))
+	for candidate := range src {
+		if strings.EqualFold(candidate, key) {
+			keys = append(keys, candidate)
+		}
+	}
+	if len(keys) == 0 {
+		return segment
+	}
+	return slices.Min(keys) + indexes
+}
@@ -0,0 +1,266 @@
+package pkg
+
+import (
+	"context"
+	"reflect"
+)
+
+// Option configures how a conversion is performed.
+type Option func(*options)
+
+// EmptyPolicy sets how empty (zero) values of the source fields are handled when marshalling.
+type EmptyPolicy int
+
+const (
+	// empty values are not set into the destination
+	OMIT_EMPTY EmptyPolicy = iota
+	// empty values are set into the destination, overriding any existing value
+	KEEP_EMPTY
+)
+
+// TagSyntax holds the operators used when parsing field tags.
+// Empty values are replaced with the package defaults.
+type TagSyntax struct {
+	// separator between the segments of a field path, defaults to PATH_SEPARATOR
+	PathSeparator string
+	// separator between the types of the type matching option, defaults to TYPES_SPLIT
+	TypesSplit string
+	// separator between a type and its path in the type matching option, defaults to TYPES_PATH_SPLIT
+	TypesPathSplit string
+	// path used when dismissing path nesting, defaults to DISMISS_NESTED
+	DismissNested string
+	// path used when setting per type path, defaults to MULTI_TYPE_NAME
+	MultiTypeName string
+}
+
+var defaultTagSyntax = TagSyntax{
+	PathSeparator:  PATH_SEPARATOR,
+	TypesSplit:     TYPES_SPLIT,
+	TypesPathSplit: TYPES_PATH_SPLIT,
+	DismissNested:  DISMISS_NESTED,
+	MultiTypeName:  MULTI_TYPE_NAME,
+}
+
+// options holds the settings of a conversion, built from the provided Option list.
+type options struct {
+	ctx     context.Context
+	vars    map[string]string
+	tagKeys []string
+	syntax  TagSyntax
+	strict  bool
+	empty   EmptyPolicy
+	types   *TypeRegistry
+	// transformers referenced from the transform<> tag option
+	transformers *TransformerRegistry
+	// type name used for type matching instead of the source or destination type name
+	typeName string
+	// tag naming the document keys of the typed ends of the conversion, instead of the json tag
+	documentTag *documentTag
+	// naming convention of the document keys
+	naming NamingStrategy
+	// match the document keys ignoring case when not found as written
+	caseInsensitive bool
+
+	// map unexported fields through their getter and setter methods
+	accessors bool
+	// number of elements converted concurrently by the batch conversions
+	workers int
+	// convert the structs declaring xml documents through encoding/xml
+	xml bool
+	// the source is a xml document, which holds every value as text and can't tell apart lists of a single element
+	xmlDocument bool
+
+	// fields resolved for every struct type, shared by the conversions using these options, see element
+	plans *fieldPlans
+
+	// structs visited while marshalling, to call their AfterMarshal hook once the destination is loaded
+	marshalled []reflect.Value
+	// order in which the document paths are first set while marshalling, to keep the fields order when possible
+	pathOrder map[string]int
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		ctx:          context.Background(),
+		tagKeys:      []string{FIELD_TAG_KEY},
+		syntax:       defaultTagSyntax,
+		types:        defaultTypeRegistry,
+		transformers: defaultTransformerRegistry,
+		plans:        &fieldPlans{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithVars sets the values of the variables used in field paths, eg sm:"spec.containers[${container}].image".
+// Every variable used in a path must be set, otherwise the conversion will fail.
+func WithVars(vars map[string]string) Option {
+	return func(o *options) {
+		o.vars = vars
+	}
+}
+
+// WithTagKey sets the struct tag key to read the field settings from, defaults to FIELD_TAG_KEY.
+// Fallback keys are read in order for the fields not declaring the tag key, so a tag profile can override only some
+// of the fields, eg WithTagKey("smv2", FIELD_TAG_KEY). Fields can be excluded from a profile using SKIP_FIELD as tag.
+func WithTagKey(key string, fallbacks ...string) Option {
+	return func(o *options) {
+		o.tagKeys = append([]string{key}, fallbacks...)
+	}
+}
+
+// WithTagSyntax overrides the operators used when parsing field tags, keeping the defaults for the empty ones.
+func WithTagSyntax(syntax TagSyntax) Option {
+	return func(o *options) {
+		setIfNotEmpty(&o.syntax.PathSeparator, syntax.PathSeparator)
+		setIfNotEmpty(&o.syntax.TypesSplit, syntax.TypesSplit)
+		setIfNotEmpty(&o.syntax.TypesPathSplit, syntax.TypesPathSplit)
+		setIfNotEmpty(&o.syntax.DismissNested, syntax.DismissNested)
+		setIfNotEmpty(&o.syntax.MultiTypeName, syntax.MultiTypeName)
+	}
+}
+
+// WithStrict makes the conversion fail when a field path can't be found on the other end: when unmarshalling, the
+// path must be present in the source, and when marshalling, the destination must declare the field set at the path.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithEmptyValues sets how empty values of the source fields are handled when marshalling, defaults to OMIT_EMPTY.
+func WithEmptyValues(policy EmptyPolicy) Option {
+	return func(o *options) {
+		o.empty = policy
+	}
+}
+
+// WithTypeRegistry sets the registry used to resolve type aliases and groups in the type matching option, defaults
+// to the registry used by RegisterTypeAlias and RegisterTypeGroup.
+func WithTypeRegistry(registry *TypeRegistry) Option {
+	return func(o *options) {
+		o.types = registry
+	}
+}
+
+// WithTransformers sets the registry used to resolve the transformers referenced from the `transform<>` tag option,
+// defaults to the registry used by RegisterTransformer.
+func WithTransformers(registry *TransformerRegistry) Option {
+	return func(o *options) {
+		o.transformers = registry
+	}
+}
+
+// AsType sets the type name of the other end of the conversion, the source when unmarshalling and the destination
+// when marshalling, used to evaluate the `types<>` tag option instead of its go type name. This is needed for type
+// matching when converting from or to raw values, like map[string]any or json documents, which have no type name.
+func AsType(name string) Option {
+	return func(o *options) {
+		o.typeName = name
+	}
+}
+
+// WithDocumentTag resolves the field paths against the document keys named by the given tag key in the source
+// struct when unmarshalling, and the destination struct when marshalling, instead of their json names. The namer
+// extracts the key name from the tag value, defaults to TagName, eg WithDocumentTag("yaml", nil) or
+// WithDocumentTag("protobuf", ProtobufJSONName). Fields without the tag use the go field name.
+func WithDocumentTag(key string, namer TagNamer) Option {
+	if namer == nil {
+		namer = TagName
+	}
+	return func(o *options) {
+		o.documentTag = &documentTag{key: key, namer: namer}
+	}
+}
+
+// WithNaming sets the naming convention of the document keys, the source when unmarshalling and the destination when
+// marshalling. The keys of the field paths are converted to it, so paths written in a convention resolve against
+// documents written in another, eg sm:"spec.clientId" reads the "client_id" key of a SNAKE_CASE source, and
+// documents are written using it. Keys taken from the documents, like the keys of map values, are kept as found.
+// Defaults to AS_WRITTEN, using the keys as written in the paths.
+func WithNaming(strategy NamingStrategy) Option {
+	return func(o *options) {
+		o.naming = strategy
+	}
+}
+
+// WithCaseInsensitiveKeys matches the keys of the field paths against the source keys ignoring case when they are not
+// found as written, eg sm:"clientId" reads the "clientID" key. When many keys match, the first one in order is used.
+func WithCaseInsensitiveKeys() Option {
+	return func(o *options) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithAccessors maps the unexported tagged fields through their getter and setter methods, so structs can keep their
+// fields private. By default a field named `name` is read with `Name()` and written with `SetName(value)`, the method
+// names can be set per field with the `get<>` and `set<>` tag options, eg sm:"metadata.name,get<GetName>".
+// Without this option unexported tagged fields make the conversion fail.
+func WithAccessors() Option {
+	return func(o *options) {
+		o.accessors = true
+	}
+}
+
+// WithWorkers spreads the elements of the batch conversions, like UnmarshalSlice, over the given number of concurrent
+// workers. Defaults to a single worker, converting the elements in order.
+func WithWorkers(workers int) Option {
+	return func(o *options) {
+		o.workers = workers
+	}
+}
+
+// WithXML converts the structs declaring the xml document they map to, by having a XMLName field or fields with xml
+// tags, through encoding/xml instead of encoding/json, the source when unmarshalling and the destination when
+// marshalling. Paths are resolved against the xml elements as in UnmarshalXML.
+func WithXML() Option {
+	return func(o *options) {
+		o.xml = true
+	}
+}
+
+// withXMLDocument marks the source as a xml document, so text values are parsed into numeric and boolean fields and
+// single values are read as one element lists, see UnmarshalXML.
+func withXMLDocument() Option {
+	return func(o *options) {
+		o.xmlDocument = true
+	}
+}
+
+// withContext sets the context of the conversion, see MarshalContext and UnmarshalContext.
+func withContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// recordPath records the document path, and every path leading to it, as set at this point of the conversion, see
+// orderKey. Paths already recorded keep their position.
+func (o *options) recordPath(path []string) {
+	if o.pathOrder == nil {
+		o.pathOrder = map[string]int{}
+	}
+	for i := range path {
+		key := orderKey(path[:i+1])
+		if _, ok := o.pathOrder[key]; !ok {
+			o.pathOrder[key] = len(o.pathOrder)
+		}
+	}
+}
+
+// element returns a copy of the options for the conversion of a batch element, without the state of any previous
+// conversion, so the options and the fields of every struct type are resolved once for the whole batch.
+func (o *options) element() *options {
+	elem := *o
+	elem.marshalled = nil
+	elem.pathOrder = nil
+	return &elem
+}
+
+func setIfNotEmpty(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
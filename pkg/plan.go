@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// fieldPlans caches the fields resolved for every struct type, so the conversions sharing the options, like the
+// elements of a batch or the conversions of a TypedMapper, parse the tags and resolve the paths of a struct type once.
+// It's safe for concurrent use.
+type fieldPlans struct {
+	plans sync.Map
+}
+
+// planKey identifies the fields of a struct type resolved against a type matching target.
+type planKey struct {
+	structType reflect.Type
+	target     typeTarget
+}
+
+// structPlan holds the fields of a struct type, resolved relative to the struct root and not bound to any value.
+type structPlan struct {
+	entries []planEntry
+}
+
+// planEntry holds a resolved field, and the plan of its struct type when it's a promoted embedded struct.
+type planEntry struct {
+	field    *Field
+	promoted *structPlan
+}
+
+// structPlan returns the plan of the struct type resolved against the target, resolving it on first use.
+// Plans are not cached when the cache is nil, or when resolving them fails.
+func (p *fieldPlans) structPlan(structType reflect.Type, target typeTarget, opts *options) (*structPlan, error) {
+	key := planKey{structType: structType, target: target}
+	if p != nil {
+		if plan, ok := p.plans.Load(key); ok {
+			return plan.(*structPlan), nil
+		}
+	}
+	plan, err := newStructPlan(structType, target, nil, opts, nil)
+	if err != nil || p == nil {
+		return plan, err
+	}
+	p.plans.Store(key, plan)
+	return plan, nil
+}
+
+// newStructPlan resolves the fields of the struct type rooted at the parents path, recursively resolving the fields
+// of the promoted embedded structs. Embedded structs already found in the chain of promoted structs are not resolved
+// again, as their fields would be shadowed anyway.
+func newStructPlan(
+	structType reflect.Type,
+	target typeTarget,
+	parents []string,
+	opts *options,
+	chain []reflect.Type,
+) (*structPlan, error) {
+	defaults, err := getStructDefaults(structType, target, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	chain = append(slices.Clip(chain), structType)
+	plan := &structPlan{}
+	for i := range structType.NumField() {
+		field := &Field{defaults: defaults, opts: opts, targetType: target.t}
+		if err := field.resolve(i, structType, target.name); err != nil {
+			return nil, err
+		}
+		if field.Skip {
+			continue
+		}
+		field.ChRoot(parents)
+
+		entry := planEntry{field: field}
+		if embedded := derefType(field.stfield.Type); field.IsPromoted() && !slices.Contains(chain, embedded) {
+			entry.promoted, err = newStructPlan(embedded, target, field.GetPathAsParent(), opts, chain)
+			if err != nil {
+				return nil, err
+			}
+		}
+		plan.entries = append(plan.entries, entry)
+	}
+	return plan, nil
+}
+
+// bind appends the plan fields, bound to the struct value and rooted at the parents path, to the provided list,
+// recursively binding the fields of the promoted embedded structs, see structFields.
+// It errors if any field path goes up beyond the document root.
+func (p *structPlan) bind(
+	structValue reflect.Value,
+	parents []string,
+	opts *options,
+	allocate bool,
+	depth int,
+	embedded []embeddedAllocation,
+	into *[]*Field,
+) error {
+	for _, entry := range p.entries {
+		field := *entry.field
+		field.opts = opts
+		field.ChRoot(parents)
+		if slices.Contains(field.Path, PARENT_PATH) {
+			return fmt.Errorf("%s: %s", ERROR_PATH_OUT_OF_ROOT, field.stfield.Name)
+		}
+		if err := field.bind(structValue); err != nil {
+			return err
+		}
+
+		if !field.IsPromoted() {
+			field.depth = depth
+			field.embedded = embedded
+			*into = append(*into, &field)
+			continue
+		}
+		if entry.promoted == nil {
+			continue
+		}
+
+		value, pending := field.Value, embedded
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() && (!allocate || !value.CanSet()) {
+				continue
+			}
+			if value.IsNil() {
+				allocation := embeddedAllocation{ptr: value, value: reflect.New(value.Type().Elem())}
+				pending = append(slices.Clip(pending), allocation)
+				value = allocation.value
+			}
+			value = value.Elem()
+		}
+		if err := entry.promoted.bind(value, parents, opts, allocate, depth+1, pending, into); err != nil {
+			return err
+		}
+	}
+	return nil
+}
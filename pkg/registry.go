@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TypeNamer can be implemented by source and destination types to report the name used when evaluating the
+// `types<>` tag option, instead of relying on the go type name.
+type TypeNamer interface {
+	SMTypeName() string
+}
+
+// TypeRegistry holds the type aliases and type groups that can be referenced from the `types<>` tag option, and the
+// unions used to map interface fields.
+// Entries are registered with the actual go types, so renaming a type won't break the mappings referencing it.
+// It is safe for concurrent use.
+type TypeRegistry struct {
+	mu      sync.RWMutex
+	aliases map[string]reflect.Type
+	groups  map[string][]reflect.Type
+	unions  map[reflect.Type]*unionType
+}
+
+// NewTypeRegistry returns an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		aliases: map[string]reflect.Type{},
+		groups:  map[string][]reflect.Type{},
+		unions:  map[reflect.Type]*unionType{},
+	}
+}
+
+// RegisterAlias registers the alias name for the type of the provided sample value, eg `RegisterAlias("deploy",
+// Deployment{})` allows using `types<deploy>` in field tags.
+// It panics when the alias is already registered for a different type.
+func (r *TypeRegistry) RegisterAlias(alias string, sample interface{}) {
+	r.registerAlias(alias, reflect.TypeOf(sample))
+}
+
+// RegisterGroup registers the group name for the types of the provided sample values, eg `RegisterGroup("workloads",
+// Deployment{}, StatefulSet{})` allows using `types<@workloads>` in field tags.
+// Registering an existing group again will add the new types to it.
+func (r *TypeRegistry) RegisterGroup(name string, samples ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sample := range samples {
+		r.groups[name] = append(r.groups[name], reflect.TypeOf(sample))
+	}
+}
+
+func (r *TypeRegistry) registerAlias(alias string, t reflect.Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if registered, ok := r.aliases[alias]; ok && registered != t {
+		msg := fmt.Sprintf("type alias '%s' already registered for %s", alias, registered.String())
+		panic(msg)
+	}
+	r.aliases[alias] = t
+}
+
+// typeTarget is the type the `types<>` tag option is evaluated against, the source when unmarshalling and the
+// destination when marshalling. The go type is unknown when the type name is set with AsType.
+type typeTarget struct {
+	name string
+	t    reflect.Type
+}
+
+// targetOf returns the type target of the value, or the one named by the AsType option when set.
+func targetOf(value interface{}, opts *options) typeTarget {
+	if opts.typeName != "" {
+		return typeTarget{name: opts.typeName}
+	}
+	if value == nil {
+		return typeTarget{}
+	}
+	t := reflect.TypeOf(value)
+	return typeTarget{name: getTypeNameOf(t), t: derefType(t)}
+}
+
+// matches reports whether the target is one of the types a `types<>` entry stands for.
+// Group references (prefixed with TYPE_GROUP_PREFIX) match every type in the group, aliases match the aliased type,
+// and anything else is considered a plain type name. Registered types are matched by identity, so other types sharing
+// their name don't match, unless the go type of the target is unknown, in which case they are matched by name.
+// It errors when referencing a group that hasn't been registered.
+func (r *TypeRegistry) matches(name string, target typeTarget) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if group, isGroup := strings.CutPrefix(name, TYPE_GROUP_PREFIX); isGroup {
+		types, ok := r.groups[group]
+		if !ok {
+			return false, fmt.Errorf("%s: %s", ERROR_UNKNOWN_TYPE_GROUP, group)
+		}
+		return slices.ContainsFunc(types, target.is), nil
+	}
+
+	if t, ok := r.aliases[name]; ok {
+		return target.is(t), nil
+	}
+
+	return name == target.name, nil
+}
+
+// is reports whether the target is the registered type, see matches.
+func (target typeTarget) is(t reflect.Type) bool {
+	if target.t == nil {
+		return getTypeNameOf(t) == target.name
+	}
+	return derefType(t) == target.t
+}
+
+var defaultTypeRegistry = NewTypeRegistry()
+
+// RegisterTypeGroup registers a named group of types in the default registry, so the whole group can be matched in
+// field tags using `types<@name>`.
+func RegisterTypeGroup(name string, samples ...interface{}) {
+	defaultTypeRegistry.RegisterGroup(name, samples...)
+}
+
+// RegisterTypeAlias registers an alias for the type T in the default registry, so it can be matched in field tags
+// using `types<alias>`.
+func RegisterTypeAlias[T any](alias string) {
+	defaultTypeRegistry.registerAlias(alias, reflect.TypeFor[T]())
+}
@@ -13,7 +13,10 @@ type TypeMatch struct {
 }
 
 type TagOpts struct {
-	MatchTypes []TypeMatch
+	MatchTypes  []TypeMatch
+	Getter      string
+	Setter      string
+	Transformer string
 }
 
 type FieldTag struct {
@@ -29,19 +32,27 @@ type FieldTag struct {
 // - TypeMatch with `Matches` property set false if no match is found but there are type-matching options set in this
 // tag field
 // - the TypeMatch description if a match is found
-func (t *FieldTag) findTypeMatch(typeName string) TypeMatch {
+//
+// Type names are resolved against the registry, so aliases and `@group` references match the types they stand for.
+func (t *FieldTag) findTypeMatch(target typeTarget, registry *TypeRegistry) (TypeMatch, error) {
 	result := TypeMatch{Matches: true}
-	if len(t.Opts.MatchTypes) > 0 && typeName != "" {
-		result.Matches = false
-		for _, match := range t.Opts.MatchTypes {
-			if match.Name == typeName {
-				result = match
-				result.Matches = true
-				break
-			}
+	if len(t.Opts.MatchTypes) == 0 || target.name == "" {
+		return result, nil
+	}
+
+	result.Matches = false
+	for _, match := range t.Opts.MatchTypes {
+		matches, err := registry.matches(match.Name, target)
+		if err != nil {
+			return result, err
+		}
+		if matches {
+			result = match
+			result.Matches = true
+			break
 		}
 	}
-	return result
+	return result, nil
 }
 
 // parseTag parses a field tag string into a FieldTag struct. The field tag string
@@ -49,40 +60,80 @@ func (t *FieldTag) findTypeMatch(typeName string) TypeMatch {
 // periods to create the Path field of the FieldTag struct. The remaining comma-
 // separated values are parsed into the Opts field of the FieldTag struct.
 //
-// If the field tag string is empty, the function returns a FieldTag with skip
+// If the field tag string is empty or SKIP_FIELD, the function returns a FieldTag with skip
 // set to true.
-func parseTag(field reflect.StructField) (FieldTag, bool) {
+// The tag keys and the separators are taken from the conversion options, the field tag string is
+// read from the first tag key declared in the field.
+func parseTag(field reflect.StructField, opts *options) (FieldTag, bool) {
 	var skip bool
 	var tag FieldTag
-	rawString := field.Tag.Get(FIELD_TAG_KEY)
-	if rawString == "" {
+	rawString := lookupTag(field, opts.tagKeys)
+	if rawString == "" || rawString == SKIP_FIELD {
 		return tag, true
 	}
 
 	tagParts := strings.Split(rawString, ",")
-	tag.Path = strings.Split(tagParts[0], ".")
+	tag.Path = splitPath(tagParts[0], opts.syntax.PathSeparator)
 	tag.RawOpts = tagParts[1:]
 
 	if len(tagParts) > 1 {
-		tag.Opts = parseTagOpts(tagParts[1:])
+		tag.Opts = parseTagOpts(tagParts[1:], opts.syntax)
 	}
 
 	return tag, skip
 }
 
+// lookupTag returns the value of the first tag key declared in the field, or an empty string if none is declared.
+func lookupTag(field reflect.StructField, keys []string) string {
+	for _, key := range keys {
+		if value, ok := field.Tag.Lookup(key); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// splitPath splits a raw tag path into its segments.
+// Leading dots are handled as parent path operators, so "..name" goes up one level before looking for name, and every
+// extra leading dot goes up one more level, eg "...name".
+func splitPath(raw string, separator string) []string {
+	trimmed := strings.TrimLeft(raw, ".")
+	path := []string{}
+	for range len(raw) - len(trimmed) - 1 {
+		path = append(path, PARENT_PATH)
+	}
+	return append(path, strings.Split(trimmed, separator)...)
+}
+
+var (
+	matchTypeRegex = regexp.MustCompile(TYPE_OPTS_REGEX)
+	getterRegex    = regexp.MustCompile(GETTER_OPTS_REGEX)
+	setterRegex    = regexp.MustCompile(SETTER_OPTS_REGEX)
+	transformRegex = regexp.MustCompile(TRANSFORM_OPTS_REGEX)
+)
+
 // parseTagOpts parses a list of tag options into a TagOpts struct.
 // The options are expected to be in the format "opt1,opt2,...".
-// The resulting TagOpts will contain a list of TypeMatch structs, one for each type option.
-func parseTagOpts(opts []string) TagOpts {
-	options := TagOpts{}
-	matchTypeRegEx := regexp.MustCompile(TYPE_OPTS_REGEX)
+// The resulting TagOpts will contain a list of TypeMatch structs, one for each type option, the accessor method names
+// set through the get<> and set<> options, and the transformer name set through the transform<> option.
+func parseTagOpts(opts []string, syntax TagSyntax) TagOpts {
+	tagOpts := TagOpts{}
 	for _, opt := range opts {
-		typeMatches := matchTypeRegEx.FindStringSubmatch(opt)
+		typeMatches := matchTypeRegex.FindStringSubmatch(opt)
 		if len(typeMatches) > 0 {
-			parseTypeMatches(typeMatches[1], &options.MatchTypes)
+			parseTypeMatches(typeMatches[1], &tagOpts.MatchTypes, syntax)
+		}
+		if getter := getterRegex.FindStringSubmatch(opt); len(getter) > 0 {
+			tagOpts.Getter = getter[1]
+		}
+		if setter := setterRegex.FindStringSubmatch(opt); len(setter) > 0 {
+			tagOpts.Setter = setter[1]
+		}
+		if transformer := transformRegex.FindStringSubmatch(opt); len(transformer) > 0 {
+			tagOpts.Transformer = transformer[1]
 		}
 	}
-	return options
+	return tagOpts
 }
 
 // parseTypeMatches parses a string representation of type matches into a slice of TypeMatch structs.
@@ -90,14 +141,14 @@ func parseTagOpts(opts []string) TagOpts {
 // Each type match consists of a type name and an optional field path, separated by a colon.
 // The field paths are split on periods to create the Path field of the TypeMatch struct.
 // The resulting slice contains one TypeMatch struct for each type match in the input string.
-func parseTypeMatches(data string, matches *[]TypeMatch) {
-	parts := strings.Split(data, "|")
+func parseTypeMatches(data string, matches *[]TypeMatch, syntax TagSyntax) {
+	parts := strings.Split(data, syntax.TypesSplit)
 	for _, typeOpt := range parts {
 		var fieldPath []string
-		typeParts := strings.Split(typeOpt, ":")
+		typeParts := strings.SplitN(typeOpt, syntax.TypesPathSplit, 2)
 		typeName := typeParts[0]
 		if len(typeParts) > 1 {
-			fieldPath = strings.Split(typeParts[1], ".")
+			fieldPath = splitPath(typeParts[1], syntax.PathSeparator)
 		}
 		*matches = append(*matches, TypeMatch{
 			Name: typeName,
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Transformer converts the values of the fields referencing it with the `transform<>` tag option, eg
+// sm:"spec.replicas,transform<quantity>". A nil function leaves the value as is in that direction.
+type Transformer struct {
+	// Marshal returns the value written to the document for the field value
+	Marshal func(ctx context.Context, value any) (any, error)
+	// Unmarshal returns the value loaded into the field for the document value, which is then set as any other
+	// document value, eg a string can be returned for a numeric field
+	Unmarshal func(ctx context.Context, value any) (any, error)
+}
+
+// TransformerRegistry holds the transformers that can be referenced from the `transform<>` tag option.
+// It is safe for concurrent use.
+type TransformerRegistry struct {
+	mu           sync.RWMutex
+	transformers map[string]Transformer
+}
+
+// NewTransformerRegistry returns an empty TransformerRegistry.
+func NewTransformerRegistry() *TransformerRegistry {
+	return &TransformerRegistry{transformers: map[string]Transformer{}}
+}
+
+// Register registers the transformer with the given name, eg `Register("quantity", Transformer{...})` allows using
+// `transform<quantity>` in field tags.
+// It panics when the name is already registered.
+func (r *TransformerRegistry) Register(name string, transformer Transformer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.transformers[name]; ok {
+		msg := fmt.Sprintf("transformer '%s' already registered", name)
+		panic(msg)
+	}
+	r.transformers[name] = transformer
+}
+
+// transformer returns the transformer registered with the name.
+// It errors when no transformer is registered with the name.
+func (r *TransformerRegistry) transformer(name string) (*Transformer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	transformer, ok := r.transformers[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", ERROR_UNKNOWN_TRANSFORMER, name)
+	}
+	return &transformer, nil
+}
+
+var defaultTransformerRegistry = NewTransformerRegistry()
+
+// RegisterTransformer registers a transformer in the default registry, so it can be referenced in field tags using
+// `transform<name>`.
+func RegisterTransformer(name string, transformer Transformer) {
+	defaultTransformerRegistry.Register(name, transformer)
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Union describes how the concrete type of the interface fields is chosen, see RegisterUnion.
+type Union struct {
+	// Discriminator is the path, relative to the field path, of the value naming the concrete type, eg "type".
+	// When empty the concrete type is chosen by which of the Variants names is present as key at the field path,
+	// and the concrete type fields are rooted at that key, eg "source.hostPath".
+	Discriminator string
+	// Variants maps every discriminator value to a sample of the concrete type, eg `"cron": CronTrigger{}`.
+	// Samples can be pointers when the interface is implemented by the pointer type.
+	Variants map[string]any
+}
+
+// unionType holds a Union registered for an interface type, with the concrete types resolved.
+type unionType struct {
+	discriminator string
+	variants      map[string]reflect.Type
+}
+
+// RegisterUnion registers the union for the interface type pointed by the sample, which is expected to be a nil
+// pointer to the interface, eg `RegisterUnion((*VolumeSource)(nil), Union{...})`.
+// It panics when the sample doesn't point to an interface, or a variant doesn't implement it.
+func (r *TypeRegistry) RegisterUnion(sample interface{}, union Union) {
+	t := reflect.TypeOf(sample)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic("union sample must be a nil pointer to an interface")
+	}
+	r.registerUnion(t.Elem(), union)
+}
+
+func (r *TypeRegistry) registerUnion(iface reflect.Type, union Union) {
+	variants := map[string]reflect.Type{}
+	for name, sample := range union.Variants {
+		t := reflect.TypeOf(sample)
+		structType := t
+		if t != nil && t.Kind() == reflect.Ptr {
+			structType = t.Elem()
+		}
+		if t == nil || !t.Implements(iface) || structType.Kind() != reflect.Struct {
+			msg := fmt.Sprintf("union variant '%s' is not a struct implementing %s", name, iface.String())
+			panic(msg)
+		}
+		variants[name] = t
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unions[iface] = &unionType{discriminator: union.Discriminator, variants: variants}
+}
+
+// union returns the union registered for the type, if any.
+func (r *TypeRegistry) union(t reflect.Type) (*unionType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	union, ok := r.unions[t]
+	return union, ok
+}
+
+// RegisterUnion registers a union for the interface type I in the default registry, so fields typed as I are
+// mapped using the concrete type chosen by the union discriminator.
+func RegisterUnion[I any](union Union) {
+	defaultTypeRegistry.RegisterUnion((*I)(nil), union)
+}
+
+// variantOf returns the discriminator value of the concrete type.
+// It errors when the type is not registered as variant of the union.
+func (u *unionType) variantOf(t reflect.Type) (string, error) {
+	for name, variant := range u.variants {
+		if variant == t {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %s", ERROR_UNKNOWN_UNION_TYPE, t.String())
+}
+
+// rootOf returns the path the fields of the named variant are rooted at.
+func (u *unionType) rootOf(name string, path []string, opts *options) []string {
+	if u.discriminator == "" {
+		return keyPath(path, opts.documentKey(name))
+	}
+	return path
+}
+
+// discriminatorPath returns the path to the discriminator of the union found at path.
+func (u *unionType) discriminatorPath(path []string, opts *options) []string {
+	discriminator := opts.documentKeys(splitPath(u.discriminator, opts.syntax.PathSeparator))
+	return append(append([]string{}, path...), discriminator...)
+}
+
+// selectVariant returns the name of the variant found at path in src, or an empty string when the discriminator is
+// not present.
+// It errors when the discriminator value is not registered as variant of the union.
+func (u *unionType) selectVariant(src map[string]interface{}, path []string, opts *options) (string, error) {
+	lookup := &Field{Path: path, opts: opts}
+	if u.discriminator == "" {
+		data, _ := lookup.GetValueFromMap(src).(map[string]interface{})
+		names := []string{}
+		for name := range u.variants {
+			if data[opts.lookupKey(data, opts.documentKey(name))] != nil {
+				names = append(names, name)
+			}
+		}
+		// keep the choice stable when several variants are present
+		slices.Sort(names)
+		if len(names) == 0 {
+			return "", nil
+		}
+		return names[0], nil
+	}
+
+	lookup.Path = u.discriminatorPath(path, opts)
+	value := lookup.GetValueFromMap(src)
+	if value == nil {
+		return "", nil
+	}
+	name := fmt.Sprint(value)
+	if _, ok := u.variants[name]; !ok {
+		return "", fmt.Errorf("%s: %s", ERROR_UNKNOWN_UNION_VARIANT, name)
+	}
+	return name, nil
+}
+
+// setDiscriminator sets the discriminator value of the named variant at path into dst, when the union uses a
+// discriminator key.
+func (u *unionType) setDiscriminator(dst map[string]interface{}, path []string, name string, opts *options) error {
+	if u.discriminator == "" {
+		return nil
+	}
+	field := &Field{
+		Path:  u.discriminatorPath(path, opts),
+		Value: reflect.ValueOf(name),
+		opts:  opts,
+	}
+	return field.SetValueIntoMap(dst)
+}
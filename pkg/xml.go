@@ -0,0 +1,232 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var xmlNameType = reflect.TypeFor[xml.Name]()
+
+// UnmarshalXML loads the jsonpath compatible destination with the values of the given xml document, see Unmarshal.
+// Paths are resolved against the elements of the document root, using XML_ATTR_PREFIX for attributes, eg
+// "item.@id", and XML_TEXT_KEY for the character data of elements having attributes or children, eg "item.#text".
+// As xml documents have no type name, use the AsType option to set the type name used for type matching.
+func UnmarshalXML(data []byte, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalXMLDocument(data, dst, opts...)
+}
+
+// MarshalXML returns the xml document generated from the given jsonpath compatible source, using root as name of
+// the document root element and as type name for type matching, see UnmarshalXML.
+// The document elements are written in the order the source fields are declared.
+func MarshalXML(src interface{}, root string, opts ...Option) ([]byte, error) {
+	return defaultMapper.MarshalXMLDocument(src, root, opts...)
+}
+
+// UnmarshalXMLDocument loads the jsonpath compatible destination with the values of the given xml document, see
+// UnmarshalXML.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) UnmarshalXMLDocument(data []byte, dst interface{}, opts ...Option) error {
+	doc, err := decodeXMLDocument(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(doc, dst, append(append([]Option{}, opts...), withXMLDocument())...)
+}
+
+// MarshalXMLDocument returns the xml document generated from the jsonpath compatible source, see MarshalXML.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalXMLDocument(src interface{}, root string, opts ...Option) ([]byte, error) {
+	encoder := &StructEncoder{}
+	if err := encoder.init(src, nil, newOptions(append(m.withOptions(opts), AsType(root))...)); err != nil {
+		return nil, err
+	}
+	out, err := encoder.RunMap()
+	if err != nil {
+		return nil, err
+	}
+	return encodeXMLDocument(root, out, encoder.opts.pathOrder)
+}
+
+// isXMLType reports whether the type is a struct declaring the xml document it maps to, by having a XMLName field or
+// fields with xml tags. Values of these types are converted through encoding/xml when the WithXML option is set.
+func isXMLType(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("xml"); ok || field.Type == xmlNameType {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlRootName returns the name of the root element of the xml type, taken from its XMLName field tag and
+// defaulting to the type name.
+func xmlRootName(t reflect.Type) string {
+	t = derefType(t)
+	if field, ok := t.FieldByName("XMLName"); ok {
+		name, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+		if name != "" {
+			// drop the namespace, eg "http://schemas.xmlsoap.org/soap/envelope/ Envelope"
+			return name[strings.LastIndex(name, " ")+1:]
+		}
+	}
+	return t.Name()
+}
+
+// decodeXMLDocument decodes the xml document into a map holding the content of its root element, see
+// decodeXMLElement. Namespaces are ignored, so elements and attributes are keyed by their local name.
+func decodeXMLDocument(r io.Reader) (document, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New(ERROR_INVALID_DOCUMENT)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root, err := decodeXMLElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			if content, ok := root.(map[string]interface{}); ok {
+				return content, nil
+			}
+			return document{XML_TEXT_KEY: root}, nil
+		}
+	}
+}
+
+// decodeXMLElement decodes the element started by the given token.
+// Elements holding only character data are decoded as the trimmed text, and any other element as a map of its
+// attributes, prefixed with XML_ATTR_PREFIX, children elements, and text under XML_TEXT_KEY. Repeated children are
+// decoded as lists.
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	values := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		values[XML_ATTR_PREFIX+attr.Name.Local] = attr.Value
+	}
+
+	text := strings.Builder{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, token)
+			if err != nil {
+				return nil, err
+			}
+			addXMLChild(values, token.Name.Local, child)
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(values) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				values[XML_TEXT_KEY] = content
+			}
+			return values, nil
+		}
+	}
+}
+
+// addXMLChild adds the child element to the values of its parent, turning the value into a list when repeated.
+func addXMLChild(values map[string]interface{}, name string, child interface{}) {
+	existing, found := values[name]
+	if !found {
+		values[name] = child
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		values[name] = append(list, child)
+		return
+	}
+	values[name] = []interface{}{existing, child}
+}
+
+// encodeXMLDocument returns the xml document with the given root element holding the content of the document map.
+func encodeXMLDocument(root string, content map[string]interface{}, order map[string]int) ([]byte, error) {
+	out := &bytes.Buffer{}
+	encoder := xml.NewEncoder(out)
+	if err := encodeXMLElement(encoder, root, content, nil, order); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encodeXMLElement writes the document value found at path as the named element, the opposite of decodeXMLElement.
+// Lists are written as repeated elements, and nil values are skipped.
+func encodeXMLElement(encoder *xml.Encoder, name string, value interface{}, path []string, order map[string]int) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, elem := range data {
+			if err := encodeXMLElement(encoder, name, elem, path, order); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		children := []string{}
+		for _, key := range sortedKeys(data, path, order) {
+			if attr, isAttr := strings.CutPrefix(key, XML_ATTR_PREFIX); isAttr {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr}, Value: xmlText(data[key])})
+			} else if key != XML_TEXT_KEY {
+				children = append(children, key)
+			}
+		}
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		if text, ok := data[XML_TEXT_KEY]; ok {
+			if err := encoder.EncodeToken(xml.CharData(xmlText(text))); err != nil {
+				return err
+			}
+		}
+		for _, key := range children {
+			if err := encodeXMLElement(encoder, key, data[key], keyPath(path, key), order); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := encoder.EncodeToken(xml.CharData(xmlText(data))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// xmlText returns the character data representing the scalar value.
+func xmlText(value interface{}) string {
+	if timestamp, ok := value.(time.Time); ok {
+		return timestamp.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
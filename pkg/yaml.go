@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML loads the jsonpath compatible destination with the values of the given yaml document, see
+// Unmarshal. When dst points to a slice, every document of a multi-document stream is loaded into a new element,
+// otherwise only the first document is loaded.
+// Values are set as decoded from the document, so yaml types without a json counterpart, like timestamps, are kept.
+// As yaml documents have no type name, use the AsType option to set the type name used for type matching.
+func UnmarshalYAML(data []byte, dst interface{}, opts ...Option) error {
+	return defaultMapper.UnmarshalYAML(data, dst, opts...)
+}
+
+// MarshalYAML returns the yaml document generated from the given jsonpath compatible source, using the type name
+// for type matching. When src is a slice or array, a multi-document stream is returned with a document for every
+// non-nil element.
+// The document keys are written in the order the source fields are declared, keys not set by a field directly, like
+// the ones of map values, are written sorted.
+func MarshalYAML(src interface{}, typeName string, opts ...Option) ([]byte, error) {
+	return defaultMapper.MarshalYAML(src, typeName, opts...)
+}
+
+// UnmarshalYAML loads the jsonpath compatible destination with the values of the given yaml document, see
+// UnmarshalYAML.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) UnmarshalYAML(data []byte, dst interface{}, opts ...Option) error {
+	if err := assertNonNilPointer(dst); err != nil {
+		return errors.New("dst must be a non-nil pointer")
+	}
+	documents, err := decodeYAMLDocuments(data)
+	if err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(dst).Elem()
+	if target.Kind() != reflect.Slice {
+		if len(documents) == 0 {
+			return nil
+		}
+		return m.Unmarshal(documents[0], dst, opts...)
+	}
+
+	result := reflect.MakeSlice(target.Type(), len(documents), len(documents))
+	for i, doc := range documents {
+		elem := result.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		} else {
+			elem = elem.Addr()
+		}
+		if err := m.Unmarshal(doc, elem.Interface(), opts...); err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+	}
+	target.Set(result)
+	return nil
+}
+
+// MarshalYAML returns the yaml document generated from the jsonpath compatible source, see MarshalYAML.
+// The provided options are applied on top of the Mapper ones.
+func (m *Mapper) MarshalYAML(src interface{}, typeName string, opts ...Option) ([]byte, error) {
+	values := []reflect.Value{reflect.ValueOf(src)}
+	if list := values[0]; list.Kind() == reflect.Slice || list.Kind() == reflect.Array {
+		values = make([]reflect.Value, list.Len())
+		for i := range list.Len() {
+			values[i] = list.Index(i)
+		}
+	}
+	sources := []interface{}{}
+	for _, value := range values {
+		// nil sources have no document to write, as nil batch elements
+		if !isNilValue(value) {
+			sources = append(sources, value.Interface())
+		}
+	}
+
+	out := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	for _, source := range sources {
+		node, err := marshalYAMLNode(source, typeName, m.withOptions(opts))
+		if err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// decodeYAMLDocuments decodes every document of the yaml stream.
+// It errors when the root of a document is not a mapping.
+func decodeYAMLDocuments(data []byte) ([]document, error) {
+	documents := []document{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		root, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: document %d", ERROR_INVALID_DOCUMENT, len(documents))
+		}
+		documents = append(documents, root)
+	}
+}
+
+// marshalYAMLNode generates the document from the source and returns it as a yaml node, keeping the order in which
+// the fields paths were set.
+func marshalYAMLNode(src interface{}, typeName string, opts []Option) (*yaml.Node, error) {
+	encoder := &StructEncoder{}
+	if err := encoder.init(src, nil, newOptions(append(append([]Option{}, opts...), AsType(typeName))...)); err != nil {
+		return nil, err
+	}
+	out, err := encoder.RunMap()
+	if err != nil {
+		return nil, err
+	}
+	return yamlNode(out, nil, encoder.opts.pathOrder)
+}
+
+// yamlNode returns the yaml node of the document value found at path, writing the mapping keys in the given paths
+// order, and sorted after them when not found.
+func yamlNode(value interface{}, path []string, order map[string]int) (*yaml.Node, error) {
+	switch data := value.(type) {
+	case map[string]interface{}:
+		keys := sortedKeys(data, path, order)
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range keys {
+			child, err := yamlNode(data[key], append(append([]string{}, path...), key), order)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, elem := range data {
+			child, err := yamlNode(elem, path, order)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	}
+
+	node := &yaml.Node{}
+	return node, node.Encode(value)
+}
@@ -1,7 +1,17 @@
 package pkg_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -398,3 +408,2039 @@ func TestStructMarshal(t *testing.T) {
 		assert.Equal(t, src.DismissNestedPointer.Direction, dst.Child.Direction)
 	})
 }
+
+// Mock types used to test the type registry
+type RegistryDeployment struct {
+	Metadata APIMetadata `json:"metadata"`
+}
+type RegistryStatefulSet struct {
+	Metadata APIMetadata `json:"metadata"`
+}
+type RegistryNamedObject struct {
+	Metadata APIMetadata `json:"metadata"`
+}
+
+func (RegistryNamedObject) SMTypeName() string {
+	return "named"
+}
+
+func TestTypeRegistry(t *testing.T) {
+	pkg.RegisterTypeGroup("workloads", RegistryDeployment{}, RegistryStatefulSet{})
+	pkg.RegisterTypeAlias[RegistryDeployment]("deploy")
+
+	t.Run("should match every type in a registered group", func(t *testing.T) {
+		type Workload struct {
+			Name string `sm:"metadata.namefield,types<@workloads>"`
+		}
+		src := Workload{Name: "test"}
+		dst1 := &RegistryDeployment{}
+		dst2 := &RegistryStatefulSet{}
+		dst3 := &APIObject{}
+
+		assert.Nil(t, pkg.Marshal(src, dst1))
+		assert.Nil(t, pkg.Marshal(src, dst2))
+		assert.Nil(t, pkg.Marshal(src, dst3))
+		assert.Equal(t, src.Name, dst1.Metadata.NameField)
+		assert.Equal(t, src.Name, dst2.Metadata.NameField)
+		assert.Empty(t, dst3.Metadata.NameField)
+	})
+	t.Run("should match registered aliases", func(t *testing.T) {
+		type Workload struct {
+			Name string `sm:"+,types<deploy:metadata.namefield>"`
+		}
+		dst := &Workload{}
+		src := RegistryDeployment{Metadata: APIMetadata{NameField: "test"}}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Metadata.NameField, dst.Name)
+	})
+	t.Run("should not match other types sharing the name of registered types", func(t *testing.T) {
+		type RegistryDeployment struct {
+			Metadata APIMetadata `json:"metadata"`
+		}
+		type Workload struct {
+			Name  string `sm:"metadata.namefield,types<deploy>"`
+			Group string `sm:"metadata.namefield,types<@workloads>"`
+		}
+		src := RegistryDeployment{Metadata: APIMetadata{NameField: "test"}}
+		dst1 := &Workload{}
+		dst2 := &Workload{}
+
+		err1 := pkg.Unmarshal(src, dst1)
+		err2 := pkg.Unmarshal(src, dst2, pkg.AsType("RegistryDeployment"))
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, Workload{}, *dst1)
+		assert.Equal(t, Workload{Name: "test", Group: "test"}, *dst2)
+	})
+	t.Run("should match the name reported by types implementing TypeNamer", func(t *testing.T) {
+		type Workload struct {
+			Name string `sm:"metadata.namefield,types<named>"`
+		}
+		src := Workload{Name: "test"}
+		dst := &RegistryNamedObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Name, dst.Metadata.NameField)
+	})
+	t.Run("should error when referencing an unknown group", func(t *testing.T) {
+		type Workload struct {
+			Name string `sm:"metadata.namefield,types<@unknown>"`
+		}
+		err := pkg.Unmarshal(RegistryDeployment{}, &Workload{})
+		assert.ErrorContains(t, err, pkg.ERROR_UNKNOWN_TYPE_GROUP)
+	})
+}
+
+// Mock a struct declaring the type matching option and root path once for all of its fields
+type SystemStructWithDefaults struct {
+	_    struct{} `sm:"metadata,types<APIObject|SecondaryAPIObject>"`
+	Name string   `sm:"namefield"`
+	Flag bool     `sm:"flag"`
+}
+type SystemStructWithPerTypeDefaults struct {
+	_    struct{} `sm:"+,types<APIObject:metadata|SecondaryAPIObject:child>"`
+	Name string   `sm:"namefield,types<APIObject>"`
+	Flag bool     `sm:"flag"`
+}
+
+func TestStructDefaults(t *testing.T) {
+	t.Run("should use the struct level path as root for every field", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{
+				NameField: "test",
+				Flag:      true,
+			},
+		}
+		dst := &SystemStructWithDefaults{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Metadata.NameField, dst.Name)
+		assert.Equal(t, src.Metadata.Flag, dst.Flag)
+	})
+	t.Run("should use the struct level types as default type matching option", func(t *testing.T) {
+		type Destination struct {
+			_    struct{} `sm:"metadata,types<SecondaryAPIObject>"`
+			Name string   `sm:"namefield"`
+			Flag bool     `sm:"flag,types<APIObject>"`
+		}
+		src := Destination{Name: "test", Flag: true}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Empty(t, dst.Metadata.NameField)
+		assert.True(t, dst.Metadata.Flag)
+	})
+	t.Run("should use the matching struct level per-type path as root", func(t *testing.T) {
+		src := SystemStructWithPerTypeDefaults{Name: "test", Flag: true}
+		dst1 := &APIObject{}
+		dst2 := &SecondaryAPIObject{}
+
+		err1 := pkg.Marshal(src, dst1)
+		err2 := pkg.Marshal(src, dst2)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, src.Name, dst1.Metadata.NameField)
+		assert.True(t, dst1.Metadata.Flag)
+		assert.Empty(t, dst2.Metadata.NameField)
+		assert.False(t, dst2.Metadata.Flag)
+	})
+	t.Run("should keep the struct level root when dismissing nesting", func(t *testing.T) {
+		type Child struct {
+			Name string `sm:"namefield"`
+		}
+		type Destination struct {
+			_     struct{} `sm:"metadata"`
+			Child Child    `sm:"->"`
+		}
+		src := Destination{Child: Child{Name: "test"}}
+		dst := &APIObject{}
+
+		assert.Nil(t, pkg.Marshal(src, dst))
+		assert.Equal(t, src.Child.Name, dst.Metadata.NameField)
+
+		decoded := &Destination{}
+		assert.Nil(t, pkg.Unmarshal(*dst, decoded))
+		assert.Equal(t, src.Child.Name, decoded.Child.Name)
+	})
+}
+
+// Mock structs reaching data outside of the subtree they are nested in
+type SystemContextNested struct {
+	Direction string `sm:"direction"`
+	Name      string `sm:"$.metadata.namefield"`
+}
+type SystemContextListed struct {
+	Direction string `sm:"config.direction"`
+	Count     int    `sm:"..somecount"`
+}
+type SystemStructWithContext struct {
+	Nested      SystemContextNested   `sm:"config.somelist[0].config"`
+	StructSlice []SystemContextListed `sm:"config.somelist"`
+}
+
+func TestPathOperators(t *testing.T) {
+	t.Run("should resolve absolute and relative paths when unmarshalling", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "test"},
+			Config: APIConfig{
+				SomeCount: 3,
+				SomeList: []APIListedObj{
+					{Config: APIListedObjConfig{Direction: "up"}},
+					{Config: APIListedObjConfig{Direction: "down"}},
+				},
+			},
+		}
+		dst := &SystemStructWithContext{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "up", dst.Nested.Direction)
+		assert.Equal(t, src.Metadata.NameField, dst.Nested.Name)
+		assert.Len(t, dst.StructSlice, 2)
+		assert.Equal(t, "down", dst.StructSlice[1].Direction)
+		assert.Equal(t, src.Config.SomeCount, dst.StructSlice[0].Count)
+		assert.Equal(t, src.Config.SomeCount, dst.StructSlice[1].Count)
+	})
+	t.Run("should resolve absolute and relative paths when marshalling", func(t *testing.T) {
+		src := SystemStructWithContext{
+			Nested: SystemContextNested{Direction: "up", Name: "test"},
+			StructSlice: []SystemContextListed{
+				{Direction: "up", Count: 3},
+				{Direction: "down"},
+			},
+		}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Nested.Name, dst.Metadata.NameField)
+		assert.Equal(t, src.StructSlice[0].Count, dst.Config.SomeCount)
+		assert.Len(t, dst.Config.SomeList, 2)
+		assert.Equal(t, "up", dst.Config.SomeList[0].Config.Direction)
+		assert.Equal(t, "down", dst.Config.SomeList[1].Config.Direction)
+	})
+	t.Run("should error when going up beyond the document root", func(t *testing.T) {
+		type Destination struct {
+			Name string `sm:"..metadata.namefield"`
+		}
+
+		err := pkg.Unmarshal(APIObject{}, &Destination{})
+
+		assert.ErrorContains(t, err, pkg.ERROR_PATH_OUT_OF_ROOT)
+	})
+	t.Run("should error when rooting values at the document root", func(t *testing.T) {
+		type Destination struct {
+			All map[string]any `sm:"$"`
+		}
+
+		err1 := pkg.Unmarshal(APIObject{}, &Destination{})
+		err2 := pkg.Marshal(Destination{All: map[string]any{"kind": "test"}}, &APIObject{})
+
+		assert.ErrorContains(t, err1, pkg.ERROR_ROOT_PATH_NOT_STRUCT)
+		assert.ErrorContains(t, err2, pkg.ERROR_ROOT_PATH_NOT_STRUCT)
+	})
+	t.Run("should root nested structs at the document root", func(t *testing.T) {
+		type Destination struct {
+			Meta struct {
+				Name string `sm:"metadata.namefield"`
+			} `sm:"$"`
+		}
+		dst := &Destination{}
+		api := &APIObject{}
+
+		err1 := pkg.Unmarshal(APIObject{Metadata: APIMetadata{NameField: "test"}}, dst)
+		err2 := pkg.Marshal(*dst, api)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, "test", dst.Meta.Name)
+		assert.Equal(t, "test", api.Metadata.NameField)
+	})
+}
+
+// Mock a struct using runtime variables in its paths
+type SystemStructWithVars struct {
+	Name      string `sm:"metadata.${field}"`
+	Direction string `sm:"config.somelist[${idx}].config.direction"`
+}
+
+func TestPathVars(t *testing.T) {
+	vars := map[string]string{"field": "namefield", "idx": "1"}
+
+	t.Run("should replace path variables when unmarshalling", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "test"},
+			Config: APIConfig{
+				SomeList: []APIListedObj{
+					{Config: APIListedObjConfig{Direction: "up"}},
+					{Config: APIListedObjConfig{Direction: "down"}},
+				},
+			},
+		}
+		dst := &SystemStructWithVars{}
+
+		err := pkg.Unmarshal(src, dst, pkg.WithVars(vars))
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Metadata.NameField, dst.Name)
+		assert.Equal(t, "down", dst.Direction)
+	})
+	t.Run("should replace path variables when marshalling", func(t *testing.T) {
+		src := SystemStructWithVars{Name: "test", Direction: "down"}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst, pkg.WithVars(vars))
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Name, dst.Metadata.NameField)
+		assert.Len(t, dst.Config.SomeList, 2)
+		assert.Equal(t, src.Direction, dst.Config.SomeList[1].Config.Direction)
+	})
+	t.Run("should error when a path variable has no value", func(t *testing.T) {
+		src := SystemStructWithVars{Name: "test"}
+
+		err := pkg.Marshal(src, &APIObject{}, pkg.WithVars(map[string]string{"idx": "0"}))
+
+		assert.ErrorContains(t, err, pkg.ERROR_UNDEFINED_PATH_VAR)
+	})
+}
+
+func TestMapper(t *testing.T) {
+	t.Run("should read the configured tag key", func(t *testing.T) {
+		type Source struct {
+			Name string `alt:"metadata.namefield"`
+			Flag bool   `sm:"metadata.flag"`
+		}
+		mapper := pkg.New(pkg.WithTagKey("alt"))
+		src := Source{Name: "test", Flag: true}
+		dst := &APIObject{}
+
+		err := mapper.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Name, dst.Metadata.NameField)
+		assert.False(t, dst.Metadata.Flag)
+	})
+	t.Run("should use the configured tag syntax", func(t *testing.T) {
+		type Destination struct {
+			Name string `sm:"~,types<SecondaryAPIObject=metadata/namefield;APIObject=metadata/namefield>"`
+		}
+		mapper := pkg.New(pkg.WithTagSyntax(pkg.TagSyntax{
+			PathSeparator:  "/",
+			TypesSplit:     ";",
+			TypesPathSplit: "=",
+			MultiTypeName:  "~",
+		}))
+		src := APIObject{Metadata: APIMetadata{NameField: "test"}}
+		dst := &Destination{}
+
+		err := mapper.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Metadata.NameField, dst.Name)
+	})
+	t.Run("should error on missing paths when strict", func(t *testing.T) {
+		type Internal struct {
+			Name string `sm:"metadata.missing"`
+		}
+		mapper := pkg.New(pkg.WithStrict())
+
+		err1 := mapper.Unmarshal(APIObject{}, &Internal{})
+		err2 := mapper.Marshal(Internal{Name: "test"}, &APIObject{})
+
+		assert.ErrorContains(t, err1, pkg.ERROR_PATH_NOT_FOUND)
+		assert.NotNil(t, err2)
+		assert.Nil(t, pkg.Unmarshal(APIObject{}, &Internal{}))
+	})
+	t.Run("should set empty values when configured", func(t *testing.T) {
+		src := SystemStruct{Name: "test"}
+		dst := &APIObject{Metadata: APIMetadata{Flag: true}}
+
+		err := pkg.New(pkg.WithEmptyValues(pkg.KEEP_EMPTY)).Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Name, dst.Metadata.NameField)
+		assert.False(t, dst.Metadata.Flag)
+	})
+	t.Run("should use the configured type registry", func(t *testing.T) {
+		type Internal struct {
+			Name string `sm:"metadata.namefield,types<api>"`
+		}
+		registry := pkg.NewTypeRegistry()
+		registry.RegisterAlias("api", APIObject{})
+		src := Internal{Name: "test"}
+		dst1 := &APIObject{}
+		dst2 := &APIObject{}
+
+		err1 := pkg.New(pkg.WithTypeRegistry(registry)).Marshal(src, dst1)
+		err2 := pkg.Marshal(src, dst2)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, src.Name, dst1.Metadata.NameField)
+		assert.Empty(t, dst2.Metadata.NameField)
+	})
+	t.Run("should allow per call options on top of the mapper ones", func(t *testing.T) {
+		type Internal struct {
+			Name string `sm:"metadata.${field}"`
+		}
+		mapper := pkg.New(pkg.WithStrict())
+		dst := &APIObject{}
+
+		err := mapper.Marshal(Internal{Name: "test"}, dst, pkg.WithVars(map[string]string{"field": "namefield"}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Metadata.NameField)
+	})
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		mapper := pkg.New()
+		errs := make(chan error, 10)
+		for i := range 10 {
+			go func() {
+				dst := &APIObject{}
+				err := mapper.Marshal(SystemStruct{Count: i}, dst)
+				if err == nil && dst.Config.SomeCount != i {
+					err = assert.AnError
+				}
+				errs <- err
+			}()
+		}
+		for range 10 {
+			assert.Nil(t, <-errs)
+		}
+	})
+}
+
+// Mock a struct whose values are converted by transformers
+type SystemTransformedStruct struct {
+	Replicas int    `sm:"spec.replicas,transform<replicas>"`
+	Name     string `sm:"metadata.name,transform<upper>"`
+}
+
+func TestTransformers(t *testing.T) {
+	registry := pkg.NewTransformerRegistry()
+	registry.Register("replicas", pkg.Transformer{
+		Marshal: func(_ context.Context, value any) (any, error) {
+			return fmt.Sprintf("%d replicas", value), nil
+		},
+		Unmarshal: func(_ context.Context, value any) (any, error) {
+			text, _ := value.(string)
+			return strconv.Atoi(strings.TrimSuffix(text, " replicas"))
+		},
+	})
+	registry.Register("upper", pkg.Transformer{
+		Marshal: func(_ context.Context, value any) (any, error) {
+			return strings.ToUpper(value.(string)), nil
+		},
+	})
+	mapper := pkg.New(pkg.WithTransformers(registry))
+	document := map[string]any{"spec": map[string]any{"replicas": "3 replicas"}, "metadata": map[string]any{"name": "A"}}
+
+	t.Run("should transform the values when marshalling", func(t *testing.T) {
+		out, err := mapper.MarshalMap(SystemTransformedStruct{Replicas: 3, Name: "a"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, document, out)
+	})
+	t.Run("should transform the values when unmarshalling", func(t *testing.T) {
+		dst := &SystemTransformedStruct{}
+
+		err := mapper.Unmarshal(document, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, SystemTransformedStruct{Replicas: 3, Name: "A"}, *dst)
+	})
+	t.Run("should report the transformer errors", func(t *testing.T) {
+		src := map[string]any{"spec": map[string]any{"replicas": "many"}}
+
+		err := mapper.Unmarshal(src, &SystemTransformedStruct{})
+
+		assert.ErrorContains(t, err, "Replicas")
+	})
+	t.Run("should error on transformers not registered", func(t *testing.T) {
+		err := pkg.Unmarshal(document, &SystemTransformedStruct{})
+
+		assert.ErrorContains(t, err, pkg.ERROR_UNKNOWN_TRANSFORMER)
+	})
+	t.Run("should pass the conversion context to the transformers", func(t *testing.T) {
+		type nameKey struct{}
+		registry := pkg.NewTransformerRegistry()
+		registry.Register("replicas", pkg.Transformer{})
+		registry.Register("upper", pkg.Transformer{
+			Unmarshal: func(ctx context.Context, _ any) (any, error) {
+				return ctx.Value(nameKey{}), nil
+			},
+		})
+		ctx := context.WithValue(context.Background(), nameKey{}, "from context")
+		src := map[string]any{"spec": map[string]any{"replicas": 3}, "metadata": map[string]any{"name": "A"}}
+		dst := &SystemTransformedStruct{}
+
+		err := pkg.New(pkg.WithTransformers(registry)).UnmarshalContext(ctx, src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "from context", dst.Name)
+	})
+}
+
+// Mock a struct mapping to different API generations through tag profiles
+type SystemStructWithProfiles struct {
+	Name      string `sm:"metadata.namefield"`
+	Flag      bool   `sm:"metadata.flag" smv2:"configflag"`
+	Direction string `sm:"config.somelist[0].config.direction" smv2:"-"`
+}
+
+func TestTagProfiles(t *testing.T) {
+	src := SystemStructWithProfiles{Name: "test", Flag: true, Direction: "up"}
+
+	t.Run("should fall back to the default tag for fields not declaring the profile", func(t *testing.T) {
+		dst := &SecondaryAPIObject{}
+
+		err := pkg.Marshal(src, dst, pkg.WithTagKey("smv2", pkg.FIELD_TAG_KEY))
+
+		assert.Nil(t, err)
+		assert.Equal(t, src.Name, dst.Metadata.NameField)
+		assert.Equal(t, src.Flag, dst.ConfigFlag)
+		assert.False(t, dst.Metadata.Flag)
+	})
+	t.Run("should not fall back for fields excluded from the profile", func(t *testing.T) {
+		dst := &APIObject{}
+		decoded := &SystemStructWithProfiles{}
+
+		assert.Nil(t, pkg.Marshal(src, dst, pkg.WithTagKey("smv2", pkg.FIELD_TAG_KEY)))
+		assert.Empty(t, dst.Config.SomeList)
+
+		assert.Nil(t, pkg.Marshal(src, dst))
+		assert.Nil(t, pkg.Unmarshal(*dst, decoded, pkg.WithTagKey("smv2", pkg.FIELD_TAG_KEY)))
+		assert.Empty(t, decoded.Direction)
+		assert.Equal(t, src.Name, decoded.Name)
+	})
+	t.Run("should only read the profile when no fallback is set", func(t *testing.T) {
+		dst := &SecondaryAPIObject{}
+
+		err := pkg.Marshal(src, dst, pkg.WithTagKey("smv2"))
+
+		assert.Nil(t, err)
+		assert.Empty(t, dst.Metadata.NameField)
+		assert.Equal(t, src.Flag, dst.ConfigFlag)
+	})
+}
+
+func TestContext(t *testing.T) {
+	t.Run("should convert using the provided context", func(t *testing.T) {
+		src := SystemStruct{Name: "test"}
+		dst := &APIObject{}
+		decoded := &SystemStruct{}
+
+		assert.Nil(t, pkg.MarshalContext(context.Background(), src, dst))
+		assert.Nil(t, pkg.UnmarshalContext(context.Background(), *dst, decoded))
+		assert.Equal(t, src.Name, decoded.Name)
+	})
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err1 := pkg.MarshalContext(ctx, SystemStruct{Name: "test"}, &APIObject{})
+		err2 := pkg.New().UnmarshalContext(ctx, APIObject{}, &SystemStruct{})
+
+		assert.ErrorIs(t, err1, context.Canceled)
+		assert.ErrorIs(t, err2, context.Canceled)
+	})
+}
+
+// Mock structs implementing the lifecycle hooks
+type HookedListed struct {
+	Direction string `sm:"config.direction"`
+}
+
+func (h *HookedListed) BeforeMarshal() error {
+	if h.Direction == "" {
+		h.Direction = "default"
+	}
+	return nil
+}
+
+func (h *HookedListed) AfterUnmarshal() error {
+	h.Direction = strings.ToUpper(h.Direction)
+	return nil
+}
+
+type HookedStruct struct {
+	Name        string         `sm:"metadata.namefield"`
+	StructSlice []HookedListed `sm:"config.somelist"`
+}
+
+func (h *HookedStruct) AfterUnmarshal() error {
+	if h.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func (h HookedStruct) AfterMarshal(dst any) error {
+	if api, ok := dst.(*APIObject); ok {
+		api.Config.SomeCount = len(h.StructSlice)
+	}
+	if out, ok := dst.(map[string]any); ok {
+		out["count"] = len(h.StructSlice)
+	}
+	return nil
+}
+
+type hookContextKey struct{}
+
+type HookedContextStruct struct {
+	Name string `sm:"metadata.namefield"`
+}
+
+func (h *HookedContextStruct) BeforeMarshalContext(ctx context.Context) error {
+	h.Name, _ = ctx.Value(hookContextKey{}).(string)
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	t.Run("should call the marshal hooks on the source and its nested structs", func(t *testing.T) {
+		src := HookedStruct{
+			Name:        "test",
+			StructSlice: []HookedListed{{Direction: "up"}, {}},
+		}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "up", dst.Config.SomeList[0].Config.Direction)
+		assert.Equal(t, "default", dst.Config.SomeList[1].Config.Direction)
+		assert.Equal(t, 2, dst.Config.SomeCount)
+	})
+	t.Run("should call the unmarshal hooks on the destination and its nested structs", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "test"},
+			Config: APIConfig{
+				SomeList: []APIListedObj{{Config: APIListedObjConfig{Direction: "up"}}},
+			},
+		}
+		dst := &HookedStruct{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "UP", dst.StructSlice[0].Direction)
+	})
+	t.Run("should return the errors of the hooks", func(t *testing.T) {
+		err := pkg.Unmarshal(APIObject{}, &HookedStruct{})
+		assert.ErrorContains(t, err, "name is required")
+	})
+	t.Run("should pass the conversion context to the hooks", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), hookContextKey{}, "test")
+		dst := &APIObject{}
+
+		err := pkg.MarshalContext(ctx, &HookedContextStruct{}, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Metadata.NameField)
+	})
+}
+
+// Mock a type controlling its own representation
+type ResourceID struct {
+	kind string
+	name string
+}
+
+func (id ResourceID) MarshalSM() (any, error) {
+	if id.kind == "" {
+		return nil, errors.New("kind is required")
+	}
+	return id.kind + "/" + id.name, nil
+}
+
+func (id *ResourceID) UnmarshalSM(value any) error {
+	raw, _ := value.(string)
+	kind, name, found := strings.Cut(raw, "/")
+	if !found {
+		return errors.New("invalid resource id")
+	}
+	id.kind = kind
+	id.name = name
+	return nil
+}
+
+type SystemStructWithIDs struct {
+	ID    ResourceID   `sm:"metadata.namefield"`
+	Refs  []ResourceID `sm:"config.somelist[0].list"`
+	Owner *ResourceID  `sm:"config.somelist[0].config.direction"`
+}
+
+func TestCustomMarshaling(t *testing.T) {
+	t.Run("should use the custom representation of the fields", func(t *testing.T) {
+		src := SystemStructWithIDs{
+			ID:    ResourceID{kind: "pod", name: "test"},
+			Refs:  []ResourceID{{kind: "svc", name: "a"}, {kind: "svc", name: "b"}},
+			Owner: &ResourceID{kind: "deploy", name: "owner"},
+		}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "pod/test", dst.Metadata.NameField)
+		assert.Equal(t, []string{"svc/a", "svc/b"}, dst.Config.SomeList[0].List)
+		assert.Equal(t, "deploy/owner", dst.Config.SomeList[0].Config.Direction)
+	})
+	t.Run("should load the fields from the raw source values", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "pod/test"},
+			Config: APIConfig{
+				SomeList: []APIListedObj{{
+					List:   []string{"svc/a"},
+					Config: APIListedObjConfig{Direction: "deploy/owner"},
+				}},
+			},
+		}
+		dst := &SystemStructWithIDs{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, ResourceID{kind: "pod", name: "test"}, dst.ID)
+		assert.Equal(t, []ResourceID{{kind: "svc", name: "a"}}, dst.Refs)
+		assert.Equal(t, &ResourceID{kind: "deploy", name: "owner"}, dst.Owner)
+	})
+	t.Run("should return the errors of the custom representation", func(t *testing.T) {
+		src := SystemStructWithIDs{Refs: []ResourceID{{name: "a"}}}
+		err1 := pkg.Marshal(src, &APIObject{})
+		err2 := pkg.Unmarshal(APIObject{Metadata: APIMetadata{NameField: "test"}}, &SystemStructWithIDs{})
+
+		assert.ErrorContains(t, err1, "kind is required")
+		assert.ErrorContains(t, err2, "invalid resource id")
+	})
+}
+
+// Mock structs using types with their own json or text representation
+type APIEventObject struct {
+	Created string         `json:"created"`
+	Address string         `json:"address"`
+	Counts  map[string]int `json:"counts"`
+}
+type SystemEvent struct {
+	Created time.Time   `sm:"created"`
+	Address net.IP      `sm:"address"`
+	Counts  map[int]int `sm:"counts"`
+}
+
+func TestStandardMarshaling(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	internal := SystemEvent{
+		Created: created,
+		Address: net.ParseIP("10.0.0.1"),
+		Counts:  map[int]int{1: 2},
+	}
+
+	t.Run("should use the json and text representation when marshalling", func(t *testing.T) {
+		dst := &APIEventObject{}
+
+		err := pkg.Marshal(internal, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, created.Format(time.RFC3339), dst.Created)
+		assert.Equal(t, "10.0.0.1", dst.Address)
+		assert.Equal(t, map[string]int{"1": 2}, dst.Counts)
+	})
+	t.Run("should use the json and text representation when unmarshalling", func(t *testing.T) {
+		src := APIEventObject{}
+		dst := &SystemEvent{}
+
+		assert.Nil(t, pkg.Marshal(internal, &src))
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.True(t, created.Equal(dst.Created))
+		assert.True(t, internal.Address.Equal(dst.Address))
+		assert.Equal(t, internal.Counts, dst.Counts)
+	})
+}
+
+// Mock structs declaring mapped fields while implementing the json marshaling interfaces
+type SystemLoggedSpec struct {
+	Name string `sm:"name"`
+}
+type SystemLoggedStruct struct {
+	Spec SystemLoggedSpec `sm:"spec"`
+}
+
+func (s SystemLoggedSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal("logged:" + s.Name)
+}
+
+func (s *SystemLoggedSpec) UnmarshalJSON([]byte) error {
+	return errors.New("not mapped through json")
+}
+
+func TestMappedStructsWithStandardMarshaling(t *testing.T) {
+	t.Run("should map the fields of structs declaring mapped fields", func(t *testing.T) {
+		src := SystemLoggedStruct{Spec: SystemLoggedSpec{Name: "x"}}
+		dst := &SystemLoggedStruct{}
+
+		out, err1 := pkg.MarshalMap(src)
+		err2 := pkg.Unmarshal(out, dst)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, map[string]any{"spec": map[string]any{"name": "x"}}, out)
+		assert.Equal(t, src, *dst)
+	})
+}
+
+// Mock structs composed from embedded types
+type SystemObjectMeta struct {
+	Name string `sm:"metadata.namefield"`
+	Flag bool   `sm:"metadata.flag"`
+}
+type SystemSpec struct {
+	Count int `sm:"config.somecount"`
+}
+type systemHidden struct {
+	Name string `sm:"metadata.namefield"`
+}
+type SystemWorkload struct {
+	SystemObjectMeta
+	*SystemSpec
+}
+type SystemWorkloadWithShadowing struct {
+	SystemObjectMeta
+	Name string `sm:"metadata.namefield"`
+}
+type SystemWorkloadWithExclusion struct {
+	SystemObjectMeta `sm:"-"`
+	SystemSpec
+}
+type SystemWorkloadWithTaggedEmbedded struct {
+	SystemNested `sm:"config.somelist[0].config"`
+}
+type SystemWorkloadWithConflict struct {
+	SystemObjectMeta
+	systemHidden
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	t.Run("should promote the embedded struct fields when marshalling", func(t *testing.T) {
+		src := SystemWorkload{
+			SystemObjectMeta: SystemObjectMeta{Name: "test", Flag: true},
+			SystemSpec:       &SystemSpec{Count: 3},
+		}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Metadata.NameField)
+		assert.True(t, dst.Metadata.Flag)
+		assert.Equal(t, 3, dst.Config.SomeCount)
+	})
+	t.Run("should promote the embedded struct fields when unmarshalling", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "test", Flag: true},
+			Config:   APIConfig{SomeCount: 3},
+		}
+		dst := &SystemWorkload{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Name)
+		assert.True(t, dst.Flag)
+		assert.Equal(t, 3, dst.Count)
+	})
+	t.Run("should skip nil embedded pointers when marshalling", func(t *testing.T) {
+		src := SystemWorkload{SystemObjectMeta: SystemObjectMeta{Name: "test"}}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Metadata.NameField)
+	})
+	t.Run("should leave nil embedded pointers nil when none of their fields is found", func(t *testing.T) {
+		src := map[string]any{"metadata": map[string]any{"namefield": "test"}}
+		dst1 := &SystemWorkload{}
+		dst2 := &SystemWorkload{}
+
+		err1 := pkg.Unmarshal(src, dst1)
+		err2 := pkg.Unmarshal(map[string]any{"config": map[string]any{"somecount": 3}}, dst2)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, "test", dst1.Name)
+		assert.Nil(t, dst1.SystemSpec)
+		assert.Equal(t, &SystemSpec{Count: 3}, dst2.SystemSpec)
+	})
+	t.Run("should use the least nested field when promoted fields share a path", func(t *testing.T) {
+		src := APIObject{Metadata: APIMetadata{NameField: "test", Flag: true}}
+		dst := &SystemWorkloadWithShadowing{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Name)
+		assert.Empty(t, dst.SystemObjectMeta.Name)
+		assert.True(t, dst.Flag)
+	})
+	t.Run("should exclude embedded structs tagged with the skip operator", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "test"},
+			Config:   APIConfig{SomeCount: 3},
+		}
+		dst := &SystemWorkloadWithExclusion{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Empty(t, dst.Name)
+		assert.Equal(t, 3, dst.Count)
+	})
+	t.Run("should handle tagged embedded structs as nested fields", func(t *testing.T) {
+		src := SystemWorkloadWithTaggedEmbedded{SystemNested: SystemNested{Direction: "up"}}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "up", dst.Config.SomeList[0].Config.Direction)
+	})
+	t.Run("should error when promoted fields conflict", func(t *testing.T) {
+		err := pkg.Marshal(SystemWorkloadWithConflict{}, &APIObject{})
+
+		assert.ErrorContains(t, err, "promoted fields resolve to the same path")
+	})
+}
+
+// Mock structs with unexported tagged fields
+type SystemStructWithUnexported struct {
+	name string `sm:"metadata.namefield"`
+}
+type SystemStructWithAccessors struct {
+	name  string `sm:"metadata.namefield"`
+	count int    `sm:"config.somecount,get<GetCount>,set<UpdateCount>"`
+}
+
+func (s *SystemStructWithAccessors) Name() string {
+	return s.name
+}
+
+func (s *SystemStructWithAccessors) SetName(name string) {
+	s.name = strings.ToLower(name)
+}
+
+func (s *SystemStructWithAccessors) GetCount() int {
+	return s.count
+}
+
+func (s *SystemStructWithAccessors) UpdateCount(count int) error {
+	if count < 0 {
+		return errors.New("count can't be negative")
+	}
+	s.count = count
+	return nil
+}
+
+func TestUnexportedFields(t *testing.T) {
+	t.Run("should error when mapping unexported fields", func(t *testing.T) {
+		err1 := pkg.Marshal(SystemStructWithUnexported{name: "test"}, &APIObject{})
+		err2 := pkg.Unmarshal(APIObject{}, &SystemStructWithUnexported{})
+
+		assert.ErrorContains(t, err1, "unexported fields can't be mapped: name")
+		assert.ErrorContains(t, err2, "unexported fields can't be mapped: name")
+	})
+	t.Run("should read the fields through their getters when marshalling", func(t *testing.T) {
+		src := SystemStructWithAccessors{name: "test", count: 3}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst, pkg.WithAccessors())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Metadata.NameField)
+		assert.Equal(t, 3, dst.Config.SomeCount)
+	})
+	t.Run("should write the fields through their setters when unmarshalling", func(t *testing.T) {
+		src := APIObject{
+			Metadata: APIMetadata{NameField: "TEST"},
+			Config:   APIConfig{SomeCount: 3},
+		}
+		dst := &SystemStructWithAccessors{}
+
+		err := pkg.Unmarshal(src, dst, pkg.WithAccessors())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Name())
+		assert.Equal(t, 3, dst.GetCount())
+	})
+	t.Run("should return the setter errors", func(t *testing.T) {
+		src := APIObject{Config: APIConfig{SomeCount: -1}}
+
+		err := pkg.Unmarshal(src, &SystemStructWithAccessors{}, pkg.WithAccessors())
+
+		assert.ErrorContains(t, err, "count can't be negative")
+	})
+	t.Run("should error when the accessors can't be found", func(t *testing.T) {
+		err := pkg.Marshal(SystemStructWithUnexported{}, &APIObject{}, pkg.WithAccessors())
+
+		assert.ErrorContains(t, err, "accessor method not found or has an invalid signature: Name, SetName")
+	})
+}
+
+// Mock structs keyed by name
+type APIVolumeSource struct {
+	Path string `json:"path"`
+}
+type APIVolume struct {
+	Source   APIVolumeSource `json:"source"`
+	ReadOnly bool            `json:"readonly"`
+}
+type APIVolumesObject struct {
+	Name    string               `json:"name"`
+	Volumes map[string]APIVolume `json:"volumes"`
+}
+type SystemVolume struct {
+	Path     string `sm:"source.path"`
+	ReadOnly bool   `sm:"readonly"`
+	Owner    string `sm:"$.name"`
+}
+type SystemVolumes struct {
+	Volumes map[string]SystemVolume `sm:"volumes"`
+}
+type SystemVolumePointers struct {
+	Volumes map[string]*SystemVolume `sm:"volumes"`
+}
+
+func TestStructMaps(t *testing.T) {
+	api := APIVolumesObject{
+		Name: "owner",
+		Volumes: map[string]APIVolume{
+			"data": {Source: APIVolumeSource{Path: "/data"}, ReadOnly: true},
+			"logs": {Source: APIVolumeSource{Path: "/logs"}},
+		},
+	}
+
+	t.Run("should map the values of a map of structs when unmarshalling", func(t *testing.T) {
+		dst := &SystemVolumes{}
+
+		err := pkg.Unmarshal(api, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]SystemVolume{
+			"data": {Path: "/data", ReadOnly: true, Owner: "owner"},
+			"logs": {Path: "/logs", Owner: "owner"},
+		}, dst.Volumes)
+	})
+	t.Run("should map the values of a map of struct pointers when unmarshalling", func(t *testing.T) {
+		dst := &SystemVolumePointers{}
+
+		err := pkg.Unmarshal(api, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &SystemVolume{Path: "/data", ReadOnly: true, Owner: "owner"}, dst.Volumes["data"])
+		assert.Equal(t, &SystemVolume{Path: "/logs", Owner: "owner"}, dst.Volumes["logs"])
+	})
+	t.Run("should map the values of a map of structs when marshalling", func(t *testing.T) {
+		src := SystemVolumes{Volumes: map[string]SystemVolume{
+			"data": {Path: "/data", ReadOnly: true, Owner: "owner"},
+			"logs": {Path: "/logs"},
+		}}
+		dst := &APIVolumesObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, api, *dst)
+	})
+	t.Run("should skip nil values of a map of struct pointers when marshalling", func(t *testing.T) {
+		src := SystemVolumePointers{Volumes: map[string]*SystemVolume{
+			"data": {Path: "/data"},
+			"logs": nil,
+		}}
+		dst := &APIVolumesObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]APIVolume{"data": {Source: APIVolumeSource{Path: "/data"}}}, dst.Volumes)
+	})
+}
+
+// Mock structs holding tagged structs in nested containers
+type APIContainersObject struct {
+	Matrix  [][]APIListedObj           `json:"matrix"`
+	Fixed   [2]APIListedObj            `json:"fixed"`
+	Grouped map[string][]*APIListedObj `json:"grouped"`
+}
+type SystemStructWithPointerSlice struct {
+	Pointers []*SystemNestedFromSlice `sm:"config.somelist2"`
+}
+type SystemStructWithContainers struct {
+	Matrix  [][]SystemNestedFromSlice           `sm:"matrix"`
+	Fixed   [2]SystemNestedFromSlice            `sm:"fixed"`
+	Grouped map[string][]*SystemNestedFromSlice `sm:"grouped"`
+}
+
+func TestNestedContainers(t *testing.T) {
+	listed := func(direction string) APIListedObj {
+		return APIListedObj{Config: APIListedObjConfig{Direction: direction}}
+	}
+	api := APIContainersObject{
+		Matrix: [][]APIListedObj{{listed("a"), listed("b")}, {listed("c")}},
+		Fixed:  [2]APIListedObj{listed("d"), listed("e")},
+		Grouped: map[string][]*APIListedObj{
+			"left": {&APIListedObj{Config: APIListedObjConfig{Direction: "f"}}},
+		},
+	}
+	internal := SystemStructWithContainers{
+		Matrix: [][]SystemNestedFromSlice{{{Direction: "a"}, {Direction: "b"}}, {{Direction: "c"}}},
+		Fixed:  [2]SystemNestedFromSlice{{Direction: "d"}, {Direction: "e"}},
+		Grouped: map[string][]*SystemNestedFromSlice{
+			"left": {{Direction: "f"}},
+		},
+	}
+
+	t.Run("should map slices of struct pointers when unmarshalling", func(t *testing.T) {
+		src := APIObject{Config: APIConfig{SomeList2: []*APIListedObj{
+			{Config: APIListedObjConfig{Direction: "up"}},
+			{Config: APIListedObjConfig{Direction: "down"}},
+		}}}
+		dst := &SystemStructWithPointerSlice{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*SystemNestedFromSlice{{Direction: "up"}, {Direction: "down"}}, dst.Pointers)
+	})
+	t.Run("should map slices of struct pointers when marshalling", func(t *testing.T) {
+		src := SystemStructWithPointerSlice{Pointers: []*SystemNestedFromSlice{{Direction: "up"}, {Direction: "down"}}}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Len(t, dst.Config.SomeList2, 2)
+		assert.Equal(t, "up", dst.Config.SomeList2[0].Config.Direction)
+		assert.Equal(t, "down", dst.Config.SomeList2[1].Config.Direction)
+	})
+	t.Run("should map nested slices, arrays and maps of slices when unmarshalling", func(t *testing.T) {
+		dst := &SystemStructWithContainers{}
+
+		err := pkg.Unmarshal(api, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, internal, *dst)
+	})
+	t.Run("should map nested slices, arrays and maps of slices when marshalling", func(t *testing.T) {
+		dst := &APIContainersObject{}
+
+		err := pkg.Marshal(internal, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, api, *dst)
+	})
+}
+
+// Mock structs with nil struct pointers
+type SystemStructWithPointers struct {
+	Nested       *SystemNested  `sm:"config.somelist[0].config"`
+	NestedChain  **SystemNested `sm:"config.somelist[0].config"`
+	Absent       *SystemNested  `sm:"config.somelist2[0].config"`
+	AbsentChain  **SystemNested `sm:"config.somelist2[0].config"`
+	Preallocated *SystemNested  `sm:"config.somelist2[0].config"`
+}
+
+func TestPointerAllocation(t *testing.T) {
+	src := APIObject{Config: APIConfig{SomeList: []APIListedObj{{
+		Config: APIListedObjConfig{
+			Direction:  "up",
+			DeepNested: APIDeepNested{Direction2: "down"},
+		},
+	}}}}
+	expected := &SystemNested{Direction: "up", DeeepNested: SystemDeepNested{Direction: "down"}}
+
+	t.Run("should allocate nil struct pointers when any child path is present", func(t *testing.T) {
+		dst := &SystemStructWithPointers{}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, dst.Nested)
+		assert.NotNil(t, dst.NestedChain)
+		assert.Equal(t, expected, *dst.NestedChain)
+	})
+	t.Run("should leave nil struct pointers when their subtree is absent", func(t *testing.T) {
+		preallocated := &SystemNested{Direction: "left"}
+		dst := &SystemStructWithPointers{Preallocated: preallocated}
+
+		err := pkg.Unmarshal(src, dst)
+
+		assert.Nil(t, err)
+		assert.Nil(t, dst.Absent)
+		assert.Nil(t, dst.AbsentChain)
+		assert.Same(t, preallocated, dst.Preallocated)
+		assert.Equal(t, "left", dst.Preallocated.Direction)
+	})
+	t.Run("should marshal pointer chains", func(t *testing.T) {
+		nested := &SystemNested{Direction: "up"}
+		dst := &APIObject{}
+
+		err := pkg.Marshal(SystemStructWithPointers{NestedChain: &nested}, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "up", dst.Config.SomeList[0].Config.Direction)
+	})
+}
+
+// Mock structs using interfaces registered as unions
+type APIHostPath struct {
+	Path string `json:"path"`
+}
+type APIEmptyDir struct {
+	Medium string `json:"medium"`
+}
+type APIVolumeSourceObj struct {
+	HostPath *APIHostPath `json:"hostPath,omitempty"`
+	EmptyDir *APIEmptyDir `json:"emptyDir,omitempty"`
+}
+type APITrigger struct {
+	Type     string `json:"type"`
+	Schedule string `json:"schedule,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+type APIPipelineObject struct {
+	Source  APIVolumeSourceObj `json:"source"`
+	Trigger *APITrigger        `json:"trigger,omitempty"`
+}
+
+type SystemVolumeSource interface {
+	isVolumeSource()
+}
+type HostPathSource struct {
+	Path string `sm:"path"`
+}
+type EmptyDirSource struct {
+	Medium string `sm:"medium"`
+}
+
+func (HostPathSource) isVolumeSource() {}
+func (EmptyDirSource) isVolumeSource() {}
+
+type SystemTrigger interface {
+	isTrigger()
+}
+type CronTrigger struct {
+	Schedule string `sm:"schedule"`
+}
+type WebhookTrigger struct {
+	URL string `sm:"url"`
+}
+
+func (CronTrigger) isTrigger()     {}
+func (*WebhookTrigger) isTrigger() {}
+
+type SystemPipeline struct {
+	Source  SystemVolumeSource `sm:"source"`
+	Trigger SystemTrigger      `sm:"trigger"`
+}
+
+func newUnionRegistry() *pkg.TypeRegistry {
+	registry := pkg.NewTypeRegistry()
+	registry.RegisterUnion((*SystemVolumeSource)(nil), pkg.Union{
+		Variants: map[string]any{"hostPath": HostPathSource{}, "emptyDir": EmptyDirSource{}},
+	})
+	registry.RegisterUnion((*SystemTrigger)(nil), pkg.Union{
+		Discriminator: "type",
+		Variants:      map[string]any{"cron": CronTrigger{}, "webhook": &WebhookTrigger{}},
+	})
+	return registry
+}
+
+func TestUnions(t *testing.T) {
+	registry := pkg.WithTypeRegistry(newUnionRegistry())
+
+	t.Run("should decode the variant chosen by the discriminator", func(t *testing.T) {
+		src := APIPipelineObject{
+			Source:  APIVolumeSourceObj{EmptyDir: &APIEmptyDir{Medium: "memory"}},
+			Trigger: &APITrigger{Type: "webhook", URL: "https://example.com"},
+		}
+		dst := &SystemPipeline{}
+
+		err := pkg.Unmarshal(src, dst, registry)
+
+		assert.Nil(t, err)
+		assert.Equal(t, EmptyDirSource{Medium: "memory"}, dst.Source)
+		assert.Equal(t, &WebhookTrigger{URL: "https://example.com"}, dst.Trigger)
+	})
+	t.Run("should leave the field nil when no variant is present", func(t *testing.T) {
+		dst := &SystemPipeline{}
+
+		err := pkg.Unmarshal(APIPipelineObject{}, dst, registry)
+
+		assert.Nil(t, err)
+		assert.Nil(t, dst.Source)
+		assert.Nil(t, dst.Trigger)
+	})
+	t.Run("should encode the variant and its discriminator", func(t *testing.T) {
+		src := SystemPipeline{
+			Source:  HostPathSource{Path: "/data"},
+			Trigger: CronTrigger{Schedule: "@daily"},
+		}
+		dst := &APIPipelineObject{}
+
+		err := pkg.Marshal(src, dst, registry)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &APIHostPath{Path: "/data"}, dst.Source.HostPath)
+		assert.Nil(t, dst.Source.EmptyDir)
+		assert.Equal(t, &APITrigger{Type: "cron", Schedule: "@daily"}, dst.Trigger)
+	})
+	t.Run("should error on unknown variants", func(t *testing.T) {
+		src := APIPipelineObject{Trigger: &APITrigger{Type: "manual"}}
+		err1 := pkg.Unmarshal(src, &SystemPipeline{}, registry)
+		err2 := pkg.Marshal(SystemPipeline{Trigger: &CronTrigger{}}, &APIPipelineObject{}, registry)
+
+		assert.ErrorContains(t, err1, "discriminator value is not registered as union variant: manual")
+		assert.ErrorContains(t, err2, "type is not registered as union variant")
+	})
+	t.Run("should register unions in the default registry", func(t *testing.T) {
+		pkg.RegisterUnion[SystemVolumeSource](pkg.Union{
+			Variants: map[string]any{"hostPath": HostPathSource{}},
+		})
+		dst := &SystemPipeline{}
+
+		err := pkg.Unmarshal(APIPipelineObject{Source: APIVolumeSourceObj{HostPath: &APIHostPath{Path: "/"}}}, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, HostPathSource{Path: "/"}, dst.Source)
+	})
+}
+
+// Mock structs holding lists of unions
+type APIPipelineWithTriggers struct {
+	Triggers []APITrigger `json:"triggers"`
+}
+type SystemPipelineWithTriggers struct {
+	Triggers []SystemTrigger `sm:"triggers"`
+}
+
+func TestUnionLists(t *testing.T) {
+	registry := pkg.WithTypeRegistry(newUnionRegistry())
+	api := APIPipelineWithTriggers{Triggers: []APITrigger{
+		{Type: "cron", Schedule: "@daily"},
+		{Type: "webhook", URL: "https://example.com"},
+	}}
+	internal := SystemPipelineWithTriggers{Triggers: []SystemTrigger{
+		CronTrigger{Schedule: "@daily"},
+		&WebhookTrigger{URL: "https://example.com"},
+	}}
+
+	t.Run("should decode every element into its own variant", func(t *testing.T) {
+		dst := &SystemPipelineWithTriggers{}
+
+		err := pkg.Unmarshal(api, dst, registry)
+
+		assert.Nil(t, err)
+		assert.Equal(t, internal, *dst)
+	})
+	t.Run("should encode every element with its discriminator", func(t *testing.T) {
+		dst := &APIPipelineWithTriggers{}
+
+		err := pkg.Marshal(internal, dst, registry)
+
+		assert.Nil(t, err)
+		assert.Equal(t, api, *dst)
+	})
+	t.Run("should error on unknown element variants", func(t *testing.T) {
+		src := APIPipelineWithTriggers{Triggers: []APITrigger{{Type: "manual"}}}
+
+		err := pkg.Unmarshal(src, &SystemPipelineWithTriggers{}, registry)
+
+		assert.ErrorContains(t, err, "discriminator value is not registered as union variant: manual")
+	})
+}
+
+func TestRawSources(t *testing.T) {
+	document := `{"metadata":{"namefield":"test","flag":true},"configflag":true,"child":{"direction":"up"}}`
+	expected := SystemStructWithMultipleDestination{
+		Name:                 "test",
+		Flag:                 true,
+		DismissNested:        NestedStructWithMultipleDestinations{Direction: "up"},
+		DismissNestedPointer: &NestedStructWithMultipleDestinations{Direction: "up"},
+	}
+	sources := map[string]any{
+		"bytes":       []byte(document),
+		"raw message": json.RawMessage(document),
+		"reader":      strings.NewReader(document),
+		"map": map[string]any{
+			"metadata":   map[string]any{"namefield": "test", "flag": true},
+			"configflag": true,
+			"child":      map[string]any{"direction": "up"},
+		},
+	}
+
+	for name, src := range sources {
+		t.Run("should unmarshal from "+name+" sources", func(t *testing.T) {
+			dst := &SystemStructWithMultipleDestination{}
+
+			err := pkg.Unmarshal(src, dst, pkg.AsType("SecondaryAPIObject"))
+
+			assert.Nil(t, err)
+			assert.Equal(t, expected, *dst)
+		})
+	}
+	t.Run("should match every type when no type name is set", func(t *testing.T) {
+		type Workload struct {
+			Name string `sm:"metadata.namefield,types<APIObject>"`
+			Flag bool   `sm:"metadata.flag,types<SecondaryAPIObject>"`
+		}
+		rawSources := []any{
+			[]byte(document),
+			json.RawMessage(document),
+			strings.NewReader(document),
+			bytes.NewReader([]byte(document)),
+			map[string]any{"metadata": map[string]any{"namefield": "test", "flag": true}},
+		}
+
+		for _, src := range rawSources {
+			dst := &Workload{}
+
+			err := pkg.Unmarshal(src, dst)
+
+			assert.Nil(t, err)
+			assert.Equal(t, Workload{Name: "test", Flag: true}, *dst)
+		}
+
+		dst := &Workload{}
+		err := pkg.UnmarshalYAML([]byte("metadata:\n  namefield: test\n  flag: true\n"), dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, Workload{Name: "test", Flag: true}, *dst)
+	})
+	t.Run("should use the type name set for type matching", func(t *testing.T) {
+		dst := &SystemStructWithMultipleDestination{}
+
+		err := pkg.Unmarshal([]byte(document), dst, pkg.AsType("APIObject"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Name)
+		assert.True(t, dst.Flag)
+		assert.Empty(t, dst.DismissNested.Direction)
+	})
+	t.Run("should error on invalid raw sources", func(t *testing.T) {
+		err := pkg.Unmarshal([]byte("{"), &SystemStruct{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRawDestinations(t *testing.T) {
+	src := SystemStructWithMultipleDestination{
+		Name:          "test",
+		Flag:          true,
+		DismissNested: NestedStructWithMultipleDestinations{Direction: "up"},
+	}
+	expected := map[string]any{
+		"metadata":   map[string]any{"namefield": "test"},
+		"configflag": true,
+		"child":      map[string]any{"direction": "up"},
+	}
+
+	t.Run("should marshal into a map", func(t *testing.T) {
+		out, err := pkg.MarshalMap(src, pkg.AsType("SecondaryAPIObject"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, out)
+	})
+	t.Run("should marshal into a json document", func(t *testing.T) {
+		out, err := pkg.MarshalJSON(src, pkg.AsType("SecondaryAPIObject"))
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"metadata":{"namefield":"test"},"configflag":true,"child":{"direction":"up"}}`, string(out))
+	})
+	t.Run("should marshal into a json document with the mapper options", func(t *testing.T) {
+		out, err := pkg.New(pkg.AsType("APIObject")).MarshalJSONDocument(src)
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"metadata":{"namefield":"test","flag":true}}`, string(out))
+	})
+	t.Run("should write the json document", func(t *testing.T) {
+		out := &strings.Builder{}
+
+		err := pkg.New(pkg.AsType("APIObject")).MarshalTo(out, src)
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"metadata":{"namefield":"test","flag":true}}`, out.String())
+	})
+	t.Run("should call the hooks with the generated map", func(t *testing.T) {
+		out, err := pkg.MarshalMap(HookedStruct{Name: "test", StructSlice: []HookedListed{{}}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, out["count"])
+	})
+}
+
+// Mock struct mapped to yaml documents
+type SystemManifest struct {
+	Name     string    `sm:"metadata.name"`
+	Replicas int       `sm:"spec.replicas"`
+	Created  time.Time `sm:"metadata.created"`
+	Image    string    `sm:"spec.template.image"`
+}
+
+func TestYAML(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	manifest := SystemManifest{Name: "test", Replicas: 2, Created: created, Image: "nginx"}
+	document := `metadata:
+  name: test
+  created: 2024-01-02T03:04:05Z
+spec:
+  replicas: 2
+  template:
+    image: nginx
+`
+
+	t.Run("should unmarshal yaml documents", func(t *testing.T) {
+		dst := &SystemManifest{}
+
+		err := pkg.UnmarshalYAML([]byte(document), dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, manifest, *dst)
+	})
+	t.Run("should unmarshal multi-document streams into slices", func(t *testing.T) {
+		stream := document + "---\n" + strings.ReplaceAll(document, "name: test", "name: other")
+		dst := []*SystemManifest{}
+
+		err := pkg.UnmarshalYAML([]byte(stream), &dst)
+
+		assert.Nil(t, err)
+		assert.Len(t, dst, 2)
+		assert.Equal(t, "test", dst[0].Name)
+		assert.Equal(t, "other", dst[1].Name)
+		assert.True(t, created.Equal(dst[1].Created))
+	})
+	t.Run("should marshal yaml documents keeping the fields order", func(t *testing.T) {
+		out, err := pkg.MarshalYAML(manifest, "Manifest")
+
+		assert.Nil(t, err)
+		assert.Equal(t, `metadata:
+  name: test
+  created: 2024-01-02T03:04:05Z
+spec:
+  replicas: 2
+  template:
+    image: nginx
+`, string(out))
+	})
+	t.Run("should marshal slices into multi-document streams", func(t *testing.T) {
+		out, err := pkg.MarshalYAML([]SystemManifest{{Name: "a"}, {Name: "b"}}, "Manifest")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "metadata:\n  name: a\n---\nmetadata:\n  name: b\n", string(out))
+	})
+	t.Run("should skip nil elements of multi-document streams", func(t *testing.T) {
+		out, err := pkg.MarshalYAML([]*SystemManifest{{Name: "a"}, nil, {Name: "b"}}, "Manifest")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "metadata:\n  name: a\n---\nmetadata:\n  name: b\n", string(out))
+	})
+	t.Run("should convert yaml documents with the mapper options", func(t *testing.T) {
+		mapper := pkg.New(pkg.WithStrict(), pkg.WithEmptyValues(pkg.KEEP_EMPTY))
+
+		out, err1 := mapper.MarshalYAML(SystemManifest{Name: "a"}, "Manifest")
+		err2 := mapper.UnmarshalYAML([]byte("metadata:\n  name: a\n"), &SystemManifest{})
+
+		assert.Nil(t, err1)
+		assert.Contains(t, string(out), "replicas: 0")
+		assert.ErrorContains(t, err2, pkg.ERROR_PATH_NOT_FOUND)
+	})
+	t.Run("should error when the document root is not a mapping", func(t *testing.T) {
+		err := pkg.UnmarshalYAML([]byte("- a\n- b\n"), &SystemManifest{})
+
+		assert.ErrorContains(t, err, "document root must be a mapping")
+	})
+}
+
+// Mock structs mapped to xml documents
+type XMLOrderItem struct {
+	SKU      string `xml:"sku,attr"`
+	Quantity int    `xml:"quantity"`
+}
+type XMLOrderNote struct {
+	Lang string `xml:"lang,attr"`
+	Text string `xml:",chardata"`
+}
+type XMLOrder struct {
+	XMLName  xml.Name       `xml:"order"`
+	ID       string         `xml:"id,attr"`
+	Customer string         `xml:"customer"`
+	Items    []XMLOrderItem `xml:"items>item"`
+	Note     XMLOrderNote   `xml:"note"`
+}
+type XMLJSONObject struct {
+	Name string `json:"name" xml:"title"`
+}
+type SystemXMLValues struct {
+	Pointer  *int   `sm:"p"`
+	Pointers []*int `sm:"l"`
+	Empty    int    `sm:"n"`
+}
+type SystemOrderLine struct {
+	SKU      string `sm:"@sku"`
+	Quantity int    `sm:"quantity"`
+}
+type SystemOrder struct {
+	ID       string            `sm:"@id"`
+	Customer string            `sm:"customer"`
+	Lines    []SystemOrderLine `sm:"items.item"`
+	Note     string            `sm:"note.#text"`
+	NoteLang string            `sm:"note.@lang"`
+}
+
+func TestXML(t *testing.T) {
+	order := XMLOrder{
+		XMLName:  xml.Name{Local: "order"},
+		ID:       "o-1",
+		Customer: "ACME",
+		Items:    []XMLOrderItem{{SKU: "a", Quantity: 1}, {SKU: "b", Quantity: 2}},
+		Note:     XMLOrderNote{Lang: "en", Text: "fragile"},
+	}
+	internal := SystemOrder{
+		ID:       "o-1",
+		Customer: "ACME",
+		Lines:    []SystemOrderLine{{SKU: "a", Quantity: 1}, {SKU: "b", Quantity: 2}},
+		Note:     "fragile",
+		NoteLang: "en",
+	}
+	document := `<order id="o-1"><customer>ACME</customer><items><item sku="a"><quantity>1</quantity></item>` +
+		`<item sku="b"><quantity>2</quantity></item></items><note lang="en">fragile</note></order>`
+
+	t.Run("should unmarshal xml tagged sources", func(t *testing.T) {
+		dst := &SystemOrder{}
+
+		err := pkg.Unmarshal(order, dst, pkg.WithXML())
+
+		assert.Nil(t, err)
+		assert.Equal(t, internal, *dst)
+	})
+	t.Run("should marshal into xml tagged destinations", func(t *testing.T) {
+		dst := &XMLOrder{}
+
+		err := pkg.Marshal(internal, dst, pkg.WithXML())
+
+		assert.Nil(t, err)
+		assert.Equal(t, order, *dst)
+	})
+	t.Run("should unmarshal raw xml documents", func(t *testing.T) {
+		dst := &SystemOrder{}
+
+		err := pkg.UnmarshalXML([]byte(document), dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, internal, *dst)
+	})
+	t.Run("should unmarshal single elements into lists", func(t *testing.T) {
+		dst := &SystemOrder{}
+		single := `<order><items><item sku="a"><quantity>1</quantity></item></items></order>`
+
+		err := pkg.UnmarshalXML([]byte(single), dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []SystemOrderLine{{SKU: "a", Quantity: 1}}, dst.Lines)
+	})
+	t.Run("should parse text values into pointers and empty elements as zero values", func(t *testing.T) {
+		dst := &SystemXMLValues{Empty: 3}
+		values := `<doc><p>5</p><l>1</l><l>2</l><n/></doc>`
+
+		err := pkg.UnmarshalXML([]byte(values), dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 5, *dst.Pointer)
+		assert.Len(t, dst.Pointers, 2)
+		assert.Equal(t, 2, *dst.Pointers[1])
+		assert.Equal(t, 0, dst.Empty)
+	})
+	t.Run("should read only the xml sources as xml documents", func(t *testing.T) {
+		src := []any{order, map[string]any{"quantity": "5"}}
+		dst := []SystemOrderLine{}
+
+		err := pkg.UnmarshalSlice(src, &dst, pkg.WithXML())
+
+		var batchErr *pkg.BatchError
+		assert.ErrorAs(t, err, &batchErr)
+		assert.Len(t, batchErr.Errors, 1)
+		assert.Contains(t, batchErr.Errors, 1)
+	})
+	t.Run("should convert xml tagged structs through json without the xml option", func(t *testing.T) {
+		dst := &SystemLoggedSpec{}
+
+		err := pkg.Unmarshal(XMLJSONObject{Name: "test"}, dst)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "test", dst.Name)
+	})
+	t.Run("should not parse text values of json documents", func(t *testing.T) {
+		dst := &SystemOrderLine{}
+
+		err := pkg.Unmarshal([]byte(`{"quantity":"5"}`), dst)
+
+		assert.NotNil(t, err)
+	})
+	t.Run("should marshal raw xml documents", func(t *testing.T) {
+		out, err := pkg.MarshalXML(internal, "order")
+
+		assert.Nil(t, err)
+		assert.Equal(t, document, string(out))
+	})
+	t.Run("should convert raw xml documents with the mapper options", func(t *testing.T) {
+		mapper := pkg.New(pkg.WithStrict())
+		dst := &SystemOrder{}
+
+		out, err1 := mapper.MarshalXMLDocument(internal, "order")
+		err2 := mapper.UnmarshalXMLDocument(out, dst)
+		err3 := mapper.UnmarshalXMLDocument([]byte(`<order id="o-1"></order>`), &SystemOrder{})
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, internal, *dst)
+		assert.NotNil(t, err3)
+	})
+}
+
+// Mock structs naming their fields through other tag keys
+type YAMLListedObj struct {
+	Direction string `yaml:"direction_name"`
+}
+type YAMLObject struct {
+	Name     string          `yaml:"display_name"`
+	Replicas int             `yaml:"replicas,omitempty"`
+	Listed   []YAMLListedObj `yaml:"listed"`
+	Ignored  string          `yaml:"-"`
+	Untagged string
+}
+type ProtoObject struct {
+	ClientID  string `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	ProtoName string `protobuf:"bytes,2,opt,name=proto_name,proto3" json:"proto_name,omitempty"`
+}
+type SystemDocumentStruct struct {
+	Name      string `sm:"display_name"`
+	Replicas  int    `sm:"replicas"`
+	Direction string `sm:"listed[0].direction_name"`
+	Untagged  string `sm:"Untagged"`
+	Ignored   string `sm:"Ignored"`
+}
+type SystemProtoStruct struct {
+	ClientID  string `sm:"clientId"`
+	ProtoName string `sm:"proto_name"`
+}
+
+func TestDocumentTag(t *testing.T) {
+	yamlTag := pkg.WithDocumentTag("yaml", nil)
+
+	t.Run("should resolve paths against the document tag when marshalling", func(t *testing.T) {
+		src := SystemDocumentStruct{Name: "test", Replicas: 2, Direction: "up", Untagged: "value", Ignored: "ignored"}
+		dst := &YAMLObject{}
+
+		err := pkg.Marshal(src, dst, yamlTag)
+
+		assert.Nil(t, err)
+		assert.Equal(t, YAMLObject{
+			Name:     "test",
+			Replicas: 2,
+			Listed:   []YAMLListedObj{{Direction: "up"}},
+			Untagged: "value",
+		}, *dst)
+	})
+	t.Run("should resolve paths against the document tag when unmarshalling", func(t *testing.T) {
+		src := YAMLObject{
+			Name:     "test",
+			Listed:   []YAMLListedObj{{Direction: "up"}},
+			Ignored:  "ignored",
+			Untagged: "value",
+		}
+		dst := &SystemDocumentStruct{}
+
+		err := pkg.Unmarshal(src, dst, yamlTag)
+
+		assert.Nil(t, err)
+		assert.Equal(t, SystemDocumentStruct{Name: "test", Direction: "up", Untagged: "value"}, *dst)
+	})
+	t.Run("should extract the names with the tag namer", func(t *testing.T) {
+		protoTag := pkg.WithDocumentTag("protobuf", pkg.ProtobufJSONName)
+		proto := ProtoObject{ClientID: "id", ProtoName: "name"}
+		internal := SystemProtoStruct{ClientID: "id", ProtoName: "name"}
+		dst1 := &SystemProtoStruct{}
+		dst2 := &ProtoObject{}
+
+		err1 := pkg.Unmarshal(proto, dst1, protoTag)
+		err2 := pkg.Marshal(internal, dst2, protoTag)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, internal, *dst1)
+		assert.Equal(t, proto, *dst2)
+	})
+	t.Run("should error on keys not declared by the destination when strict", func(t *testing.T) {
+		src := SystemDocumentStruct{Name: "test", Ignored: "ignored"}
+		internal := SystemProtoStruct{ClientID: "id", ProtoName: "name"}
+
+		err1 := pkg.Marshal(src, &YAMLObject{}, yamlTag, pkg.WithStrict())
+		err2 := pkg.Marshal(internal, &ProtoObject{}, pkg.WithDocumentTag("protobuf", pkg.ProtobufJSONName), pkg.WithStrict())
+
+		assert.ErrorContains(t, err1, pkg.ERROR_PATH_NOT_DECLARED)
+		assert.ErrorContains(t, err1, "Ignored")
+		assert.Nil(t, err2)
+	})
+}
+
+// Mock structs for naming strategies
+type SystemNamingStruct struct {
+	ClientID string   `sm:"spec.clientId"`
+	Servers  []string `sm:"spec.httpServers"`
+	Region   string   `sm:"metadata.regionName"`
+}
+type SystemNamingVolume struct {
+	SizeGB int `sm:"size_gb"`
+}
+type SystemNamingVolumes struct {
+	Volumes map[string]SystemNamingVolume `sm:"data_volumes"`
+}
+
+func TestNaming(t *testing.T) {
+	t.Run("should write names in the naming strategy", func(t *testing.T) {
+		cases := []struct {
+			strategy pkg.NamingStrategy
+			name     string
+			expected string
+		}{
+			{pkg.AS_WRITTEN, "clientID", "clientID"},
+			{pkg.SNAKE_CASE, "clientID", "client_id"},
+			{pkg.SNAKE_CASE, "HTTPServer", "http_server"},
+			{pkg.CAMEL_CASE, "client_id", "clientId"},
+			{pkg.CAMEL_CASE, "Client-ID", "clientId"},
+			{pkg.KEBAB_CASE, "clientId", "client-id"},
+			{pkg.SCREAMING_CASE, "clientId2", "CLIENT_ID2"},
+			{pkg.SNAKE_CASE, "->", "->"},
+		}
+		for _, c := range cases {
+			assert.Equal(t, c.expected, c.strategy.Apply(c.name))
+		}
+	})
+	t.Run("should resolve paths against sources in the naming strategy", func(t *testing.T) {
+		src := map[string]any{
+			"spec":     map[string]any{"client_id": "id", "http_servers": []any{"a", "b"}},
+			"metadata": map[string]any{"region_name": "eu"},
+		}
+		dst := &SystemNamingStruct{}
+
+		err := pkg.Unmarshal(src, dst, pkg.WithNaming(pkg.SNAKE_CASE))
+
+		assert.Nil(t, err)
+		assert.Equal(t, SystemNamingStruct{ClientID: "id", Servers: []string{"a", "b"}, Region: "eu"}, *dst)
+	})
+	t.Run("should write keys in the naming strategy", func(t *testing.T) {
+		src := SystemNamingStruct{ClientID: "id", Servers: []string{"a"}, Region: "eu"}
+
+		out, err := pkg.MarshalMap(src, pkg.WithNaming(pkg.KEBAB_CASE))
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]any{
+			"spec":     map[string]any{"client-id": "id", "http-servers": []any{"a"}},
+			"metadata": map[string]any{"region-name": "eu"},
+		}, out)
+	})
+	t.Run("should match keys ignoring case", func(t *testing.T) {
+		src := map[string]any{
+			"Spec":     map[string]any{"clientID": "id", "HTTPServers": []any{"a"}},
+			"metadata": map[string]any{"regionname": "eu"},
+		}
+		dst1 := &SystemNamingStruct{}
+		dst2 := &SystemNamingStruct{}
+
+		err1 := pkg.Unmarshal(src, dst1, pkg.WithCaseInsensitiveKeys())
+		err2 := pkg.Unmarshal(src, dst2)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, SystemNamingStruct{ClientID: "id", Servers: []string{"a"}, Region: "eu"}, *dst1)
+		assert.Equal(t, SystemNamingStruct{}, *dst2)
+	})
+	t.Run("should keep the map keys as found", func(t *testing.T) {
+		src := map[string]any{"dataVolumes": map[string]any{"v1.2": map[string]any{"sizeGb": 5}}}
+		dst := &SystemNamingVolumes{}
+
+		err := pkg.Unmarshal(src, dst, pkg.WithNaming(pkg.CAMEL_CASE))
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]SystemNamingVolume{"v1.2": {SizeGB: 5}}, dst.Volumes)
+	})
+	t.Run("should write the map keys as found", func(t *testing.T) {
+		src := SystemNamingVolumes{Volumes: map[string]SystemNamingVolume{"dataVol": {SizeGB: 1}}}
+
+		out, err := pkg.MarshalMap(src, pkg.WithNaming(pkg.SNAKE_CASE))
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]any{"data_volumes": map[string]any{"dataVol": map[string]any{"size_gb": int64(1)}}}, out)
+	})
+	t.Run("should keep the xml attributes and text as written", func(t *testing.T) {
+		document := `<order><note lang="en">fragile</note></order>`
+		dst := &SystemOrder{}
+
+		err := pkg.UnmarshalXML([]byte(document), dst, pkg.WithNaming(pkg.SNAKE_CASE))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "fragile", dst.Note)
+		assert.Equal(t, "en", dst.NoteLang)
+	})
+}
+
+func TestBatches(t *testing.T) {
+	apiObject := func(name string, count int) APIObject {
+		return APIObject{Metadata: APIMetadata{NameField: name}, Config: APIConfig{SomeCount: count}}
+	}
+
+	t.Run("should convert every element of slices", func(t *testing.T) {
+		src := []SystemStruct{{Name: "a", Count: 1}, {Name: "b", Count: 2}, {Name: "c", Count: 3}}
+		dst := []*APIObject{}
+		decoded := []SystemStruct{{Name: "stale"}}
+
+		err1 := pkg.MarshalSlice(src, &dst, pkg.WithWorkers(2))
+		err2 := pkg.UnmarshalSlice(dst, &decoded)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Len(t, dst, 3)
+		assert.Equal(t, apiObject("b", 2), *dst[1])
+		assert.Equal(t, []string{"a", "b", "c"}, []string{decoded[0].Name, decoded[1].Name, decoded[2].Name})
+		assert.Equal(t, []int{1, 2, 3}, []int{decoded[0].Count, decoded[1].Count, decoded[2].Count})
+	})
+	t.Run("should convert every value of maps", func(t *testing.T) {
+		src := map[string]APIObject{"a": apiObject("a", 1), "b": apiObject("b", 2)}
+		dst := map[string]SystemStruct{}
+		encoded := map[string]APIObject(nil)
+
+		err1 := pkg.UnmarshalMapValues(src, &dst)
+		err2 := pkg.MarshalMapValues(dst, &encoded)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, "b", dst["b"].Name)
+		assert.Equal(t, 1, dst["a"].Count)
+		assert.Equal(t, src, encoded)
+	})
+	t.Run("should report the errors of every failed element", func(t *testing.T) {
+		src := []APIObject{apiObject("a", -1), apiObject("b", 2), apiObject("c", -3)}
+		dst := []SystemStructWithAccessors{}
+
+		err := pkg.UnmarshalSlice(src, &dst, pkg.WithAccessors(), pkg.WithWorkers(3))
+
+		var batchErr *pkg.BatchError
+		assert.ErrorAs(t, err, &batchErr)
+		assert.Len(t, batchErr.Errors, 2)
+		assert.Contains(t, batchErr.Errors, 0)
+		assert.Contains(t, batchErr.Errors, 2)
+		assert.Equal(t, 2, dst[1].GetCount())
+		assert.ErrorContains(t, err, pkg.ERROR_BATCH_FAILED)
+	})
+	t.Run("should error on invalid batches", func(t *testing.T) {
+		err1 := pkg.UnmarshalSlice(APIObject{}, &[]SystemStruct{})
+		err2 := pkg.UnmarshalSlice([]APIObject{}, []SystemStruct{})
+		err3 := pkg.MarshalMapValues(map[string]SystemStruct{}, &map[int]APIObject{})
+
+		assert.EqualError(t, err1, pkg.ERROR_INVALID_SLICE_BATCH)
+		assert.EqualError(t, err2, pkg.ERROR_INVALID_SLICE_BATCH)
+		assert.EqualError(t, err3, pkg.ERROR_INVALID_MAP_BATCH)
+	})
+	t.Run("should bind the resolved fields to every element", func(t *testing.T) {
+		src := []map[string]any{
+			{"metadata": map[string]any{"namefield": "a"}},
+			{"config": map[string]any{"somecount": 2}},
+			{"metadata": map[string]any{"namefield": "c"}, "config": map[string]any{"somecount": 3}},
+		}
+		dst := []SystemWorkload{}
+
+		err := pkg.UnmarshalSlice(src, &dst, pkg.WithWorkers(2))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "", "c"}, []string{dst[0].Name, dst[1].Name, dst[2].Name})
+		assert.Nil(t, dst[0].SystemSpec)
+		assert.Equal(t, 2, dst[1].Count)
+		assert.Equal(t, 3, dst[2].Count)
+		assert.NotSame(t, dst[1].SystemSpec, dst[2].SystemSpec)
+	})
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := pkg.MarshalSliceContext(ctx, []SystemStruct{{Name: "a"}}, &[]APIObject{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTypedConversions(t *testing.T) {
+	system := SystemStruct{Name: "test", Count: 2, Flag: true}
+	api := APIObject{Metadata: APIMetadata{NameField: "test", Flag: true}, Config: APIConfig{SomeCount: 2}}
+
+	t.Run("should convert into new values", func(t *testing.T) {
+		encoded, err1 := pkg.Convert[APIObject](system)
+		decoded, err2 := pkg.Convert[*SystemStruct](api)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, api, encoded)
+		assert.Equal(t, system.Name, decoded.Name)
+		assert.Equal(t, system.Count, decoded.Count)
+	})
+	t.Run("should convert both ways with typed mappers", func(t *testing.T) {
+		mapper := pkg.NewMapper[SystemStruct, *APIObject]()
+
+		encoded, err1 := mapper.To(system)
+		decoded, err2 := mapper.From(encoded)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, api, *encoded)
+		assert.Equal(t, system.Flag, decoded.Flag)
+		assert.Equal(t, system.Count, decoded.Count)
+	})
+	t.Run("should report the resolution errors on every conversion of typed mappers", func(t *testing.T) {
+		mapper := pkg.NewMapper[SystemStructWithVars, APIObject]()
+
+		_, err1 := mapper.To(SystemStructWithVars{Name: "test"})
+		_, err2 := mapper.From(api)
+
+		assert.ErrorContains(t, err1, pkg.ERROR_UNDEFINED_PATH_VAR)
+		assert.ErrorContains(t, err2, pkg.ERROR_UNDEFINED_PATH_VAR)
+	})
+	t.Run("should return the zero value on errors", func(t *testing.T) {
+		decoded, err := pkg.Convert[*SystemStructWithUnexported](api)
+
+		assert.ErrorContains(t, err, pkg.ERROR_UNEXPORTED_FIELD)
+		assert.Nil(t, decoded)
+	})
+	t.Run("should error when the destination is not a non-nil pointer", func(t *testing.T) {
+		err1 := pkg.Marshal(system, APIObject{})
+		err2 := pkg.Marshal(system, nil)
+		err3 := pkg.Marshal(system, (*APIObject)(nil))
+
+		assert.EqualError(t, err1, "dst must be a non-nil pointer")
+		assert.EqualError(t, err2, "dst must be a non-nil pointer")
+		assert.EqualError(t, err3, "dst must be a non-nil pointer")
+	})
+}